@@ -2,14 +2,13 @@ package app
 
 import (
 	"context"
+	"errors"
 	"flag"
 	"fmt"
-	"os"
 	"time"
 
 	"github.com/peterbourgon/ff/v3/ffcli"
 	ptr "github.com/sigstore/protobuf-specs/gen/pb-go/trustroot/v1"
-	"google.golang.org/protobuf/encoding/protojson"
 	"google.golang.org/protobuf/types/known/timestamppb"
 )
 
@@ -36,24 +35,43 @@ func Add() *ffcli.Command {
 		end     = flagset.String("end", "", "Validity end time")
 		padding = flagset.String("padding", "pkcs1v15", "For RSA key, the padding scheme to use. PKCS#1 v1.5 is the default, pss is also supported")
 		prevEnd = flagset.String("prev-end", "", "End time for currently valid chain")
+		note    = flagset.String("note-verifier", "", "Signed note verifier of a tlog, instead of -pem")
 		verbose = flagset.Bool("verbose", false, "verbose mode")
+		in      = addInputFlags(flagset)
+		out     = addOutputFlags(flagset)
 	)
 
 	return &ffcli.Command{
 		Name:       "add",
 		ShortUsage: "trtool add -uri foo.bar -ca file.pem",
 		ShortHelp:  "Add a certificate chain to a CA",
-		LongHelp:   "Add a certificate chain to a CA. If no start time is set, current time is used. If no Previous end is set, the next chain's start time is used",
-		FlagSet:    flagset,
+		LongHelp: `Add a certificate chain to a CA. If no start time is set, current time
+is used. If no Previous end is set, the next chain's start time is used.
+For tlogs and ctlogs only the open entries with the same uri are ended.
+A tlog can be added from the signed note verifier of its checkpoints,
+origin+keyhash+key, instead of a PEM key. The log id is then the SHA-256
+of the origin and key, the checkpoint key id its first four bytes, and the
+uri defaults to https:// and the origin.
+Only Ed25519 verifiers are supported.`,
+		FlagSet: flagset,
 		Exec: func(ctx context.Context, args []string) error {
 			if *nType != TypeCA && *nType != TypeTSA && *nType != TypeTLog && *nType != TypeCTLog {
 				return flag.ErrHelp
 			}
-			if *uri == "" {
-				return fmt.Errorf("no uri provided: %w", flag.ErrHelp)
-			}
-			if *pemFile == "" {
-				return fmt.Errorf("no pem file provided: %w", flag.ErrHelp)
+			if *note != "" {
+				if *nType != TypeTLog {
+					return fmt.Errorf("note verifiers are only supported for tlogs: %w", flag.ErrHelp)
+				}
+				if *pemFile != "" {
+					return fmt.Errorf("both pem file and note verifier provided: %w", flag.ErrHelp)
+				}
+			} else {
+				if *uri == "" {
+					return fmt.Errorf("no uri provided: %w", flag.ErrHelp)
+				}
+				if *pemFile == "" {
+					return fmt.Errorf("no pem file provided: %w", flag.ErrHelp)
+				}
 			}
 			if *tr == "" {
 				return fmt.Errorf("no trusted root path provided: %w", flag.ErrHelp)
@@ -62,14 +80,13 @@ func Add() *ffcli.Command {
 				return fmt.Errorf("invalid RSA padding: %w", flag.ErrHelp)
 			}
 
-			return AddCmd(*tr, *nType, *uri, *pemFile, *start, *end, *prevEnd, *padding, *verbose)
+			return AddCmd(*tr, *nType, *uri, *pemFile, *note, *start, *end, *prevEnd, *padding, *verbose, *in, *out)
 		},
 	}
 }
 
-func AddCmd(trp, nType, uri, pemFile, start, end, prevEnd, padding string, verbose bool) error {
-	var tr ptr.TrustedRoot
-	var buf []byte
+func AddCmd(trp, nType, uri, pemFile, noteVerifier, start, end, prevEnd, padding string, verbose bool, in InputOptions, out OutputOptions) error {
+	var tr *ptr.TrustedRoot
 	var prevEndTs time.Time
 	var err error
 
@@ -79,24 +96,30 @@ func AddCmd(trp, nType, uri, pemFile, start, end, prevEnd, padding string, verbo
 		}
 	}
 
-	if buf, err = os.ReadFile(trp); err != nil {
-		return fmt.Errorf("Could not read trusted root %s: %w",
-			trp, err)
-	}
-
-	if err = protojson.Unmarshal(buf, &tr); err != nil {
-		return fmt.Errorf("failed to unmarhsal trusted root: %w", err)
+	if tr, err = readTrustedRoot(trp, in); err != nil {
+		return err
 	}
 
 	switch nType {
 	case TypeCA:
 		fallthrough
 	case TypeTSA:
-		err = addCA(&tr, nType, uri, pemFile, start, end, prevEndTs, verbose)
+		err = addCA(tr, nType, uri, pemFile, start, end, prevEndTs, verbose)
 	case TypeCTLog:
 		fallthrough
 	case TypeTLog:
-		err = addTLog(&tr, nType, uri, pemFile, start, end, prevEndTs, padding, verbose)
+		var newtl *ptr.TransparencyLogInstance
+		if noteVerifier != "" {
+			if v, ok := versionOf(KindTrustedRoot, tr.GetMediaType()); ok && v.Version == "0.1" {
+				return errors.New("checkpoint key ids are not supported by v0.1, migrate the trusted root to v0.2")
+			}
+			newtl, err = newTLogFromNoteVerifier(noteVerifier, start, end, uri)
+		} else {
+			newtl, err = newTLog(pemFile, start, end, uri, padding, verbose)
+		}
+		if err == nil {
+			addTLog(tr, nType, newtl, prevEndTs)
+		}
 	default:
 		return flag.ErrHelp
 	}
@@ -104,15 +127,9 @@ func AddCmd(trp, nType, uri, pemFile, start, end, prevEnd, padding string, verbo
 	if err != nil {
 		return err
 	}
-	// Marshal to JSON and print to stdout
-
-	if buf, err = protojson.Marshal(&tr); err != nil {
-		return err
-	}
 
-	fmt.Println(string(buf))
-
-	return nil
+	// Marshal to JSON and print to stdout
+	return printProto(tr, out)
 }
 
 func addCA(tr *ptr.TrustedRoot, caType, uri, pemFile, start, end string,
@@ -152,39 +169,30 @@ func addCA(tr *ptr.TrustedRoot, caType, uri, pemFile, start, end string,
 	return nil
 }
 
-func addTLog(tr *ptr.TrustedRoot, tlogType, uri, pemFile, start, end string,
-	prevEndTs time.Time, padding string, verbose bool) error {
-	var newtl *ptr.TransparencyLogInstance
+// addTLog adds the log and ends the open entries with the same base
+// URL at prevEndTs, or the start of the new entry. Logs with other URLs,
+// such as a Rekor v2 log next to v1 or the next shard, are left open.
+func addTLog(tr *ptr.TrustedRoot, tlogType string, newtl *ptr.TransparencyLogInstance, prevEndTs time.Time) {
 	var tlog *[]*ptr.TransparencyLogInstance
-	var err error
 
-	if newtl, err = newTLog(pemFile, start, end, uri, padding, verbose); err != nil {
-		return err
-	}
-
-	// Close previous entry if timestamp is open
 	if tlogType == TypeTLog {
 		tlog = &tr.Tlogs
 	} else {
 		tlog = &tr.Ctlogs
 	}
-	var last = len(*tlog) - 1
-	if last >= 0 {
-		if (*tlog)[last].PublicKey.ValidFor.End == nil {
-			if prevEndTs.IsZero() {
-				(*tlog)[last].PublicKey.ValidFor.End = newtl.PublicKey.ValidFor.Start
-			} else {
-				(*tlog)[last].PublicKey.ValidFor.End = timestamppb.New(prevEndTs)
-			}
+
+	// Close previous entries of the log if timestamp is open
+	var end = newtl.PublicKey.ValidFor.Start
+	if !prevEndTs.IsZero() {
+		end = timestamppb.New(prevEndTs)
+	}
+	for _, tl := range *tlog {
+		vf := tl.GetPublicKey().GetValidFor()
+		if tl.GetBaseUrl() == newtl.GetBaseUrl() && vf != nil && vf.End == nil {
+			vf.End = end
 		}
 	}
 
 	// Add new entry
-	if tlogType == TypeTLog {
-		tr.Tlogs = append(*tlog, newtl)
-	} else {
-		tr.Ctlogs = append(*tlog, newtl)
-	}
-
-	return nil
+	*tlog = append(*tlog, newtl)
 }
@@ -0,0 +1,40 @@
+package app
+
+import (
+	"testing"
+	"time"
+
+	pc "github.com/sigstore/protobuf-specs/gen/pb-go/common/v1"
+	ptr "github.com/sigstore/protobuf-specs/gen/pb-go/trustroot/v1"
+	"github.com/stretchr/testify/assert"
+	"google.golang.org/protobuf/types/known/timestamppb"
+)
+
+func TestAddTLog(t *testing.T) {
+	tlog := func(uri string, start time.Time) *ptr.TransparencyLogInstance {
+		return &ptr.TransparencyLogInstance{
+			BaseUrl:   uri,
+			PublicKey: &pc.PublicKey{ValidFor: &pc.TimeRange{Start: timestamppb.New(start)}},
+		}
+	}
+	var v1 = time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	var v2 = time.Date(2025, 6, 1, 0, 0, 0, 0, time.UTC)
+	var tr ptr.TrustedRoot
+
+	addTLog(&tr, TypeTLog, tlog("https://rekor.test", v1), time.Time{})
+	// A log with another URL, like Rekor v2, leaves v1 open
+	addTLog(&tr, TypeTLog, tlog("https://log2025-1.rekor.test", v2), time.Time{})
+	assert.Len(t, tr.GetTlogs(), 2)
+	assert.Nil(t, tr.GetTlogs()[0].GetPublicKey().GetValidFor().GetEnd())
+
+	// A new key of the log ends the open one
+	addTLog(&tr, TypeTLog, tlog("https://rekor.test", v2), time.Time{})
+	assert.Equal(t, v2, tr.GetTlogs()[0].GetPublicKey().GetValidFor().GetEnd().AsTime())
+	assert.Nil(t, tr.GetTlogs()[1].GetPublicKey().GetValidFor().GetEnd())
+
+	prevEnd := v2.AddDate(0, 1, 0)
+	addTLog(&tr, TypeTLog, tlog("https://log2025-1.rekor.test", v2), prevEnd)
+	assert.Equal(t, prevEnd, tr.GetTlogs()[1].GetPublicKey().GetValidFor().GetEnd().AsTime())
+	assert.Nil(t, tr.GetTlogs()[2].GetPublicKey().GetValidFor().GetEnd())
+	assert.Empty(t, tr.GetCtlogs())
+}
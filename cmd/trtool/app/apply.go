@@ -0,0 +1,359 @@
+package app
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/peterbourgon/ff/v3/ffcli"
+	pc "github.com/sigstore/protobuf-specs/gen/pb-go/common/v1"
+	ptr "github.com/sigstore/protobuf-specs/gen/pb-go/trustroot/v1"
+	"google.golang.org/protobuf/types/known/timestamppb"
+	"gopkg.in/yaml.v3"
+)
+
+const (
+	OpAdd         = "add"
+	OpRotate      = "rotate"
+	OpRemove      = "remove"
+	OpSetValidity = "set-validity"
+)
+
+// Change is an operation on a trusted root entry. Entries are
+// selected by type and URI, and by Index among the entries with that
+// URI if there are several.
+type Change struct {
+	Op            string `yaml:"op"`
+	Type          string `yaml:"type"`
+	ManifestEntry `yaml:",inline"`
+	PrevEnd       string `yaml:"prevEnd"`
+	Index         *int   `yaml:"index"`
+}
+
+func Apply() *ffcli.Command {
+	var (
+		flagset = flag.NewFlagSet("trtool apply", flag.ExitOnError)
+		file    = flagset.String("f", "trusted_root.json", "Trusted root to update")
+		changes = flagset.String("changes", "", "YAML file with the changes to apply")
+		dryRun  = flagset.Bool("dry-run", false, "Print the result instead of updating the trusted root")
+		verbose = flagset.Bool("v", false, "verbose mode")
+		in      = addInputFlags(flagset)
+		out     = addOutputFlags(flagset)
+	)
+
+	return &ffcli.Command{
+		Name:       "apply",
+		ShortUsage: "trtool apply -f trusted_root.json -changes ops.yaml",
+		ShortHelp:  "Apply a batch of changes to a trusted root",
+		LongHelp: `Apply a list of changes to a trusted root, in order, and update the
+file in place. The result is verified before it is written, if any change
+or the verification fails the trusted root is left untouched. The
+operations are:
+  add           add an entry, like build does for a manifest entry
+  rotate        add an entry and end the open entries with the same URI at
+                prevEnd, or the start of the new entry
+  remove        remove the entry with the URI
+  set-validity  set the start and/or end of the entry with the URI
+See the README for the format.`,
+		FlagSet: flagset,
+		Exec: func(ctx context.Context, args []string) error {
+			if *changes == "" {
+				return fmt.Errorf("no changes provided: %w", flag.ErrHelp)
+			}
+
+			return ApplyCmd(os.Stdout, *file, *changes, *dryRun, *verbose, *in, *out)
+		},
+	}
+}
+
+func ApplyCmd(w io.Writer, p, changesPath string, dryRun, verbose bool, in InputOptions, out OutputOptions) error {
+	tr, err := readTrustedRoot(p, in)
+	if err != nil {
+		return err
+	}
+	b, err := os.ReadFile(changesPath)
+	if err != nil {
+		return fmt.Errorf("could not read changes %s: %w", changesPath, err)
+	}
+	changes, err := parseChanges(b)
+	if err != nil {
+		return fmt.Errorf("invalid changes %s: %w", changesPath, err)
+	}
+
+	dir := filepath.Dir(changesPath)
+	for i, c := range changes {
+		if err = applyChange(tr, c, dir, verbose); err != nil {
+			return fmt.Errorf("change %d (%s %s): %w", i, c.Op, c.Type, err)
+		}
+	}
+
+	if v, ok := versionOf(KindTrustedRoot, tr.GetMediaType()); ok && v.Version == "0.1" {
+		if err = checkV01Fields(tr); err != nil {
+			return fmt.Errorf("the result is not a valid v0.1 trusted root, %s is left untouched:\n%w", p, err)
+		}
+	}
+
+	var findings bytes.Buffer
+	if !VerifyTrustedRoot(&findings, tr, false) {
+		return fmt.Errorf("the result does not verify, %s is left untouched:\n%s", p,
+			strings.TrimSpace(findings.String()))
+	}
+	if fs := checkValidity(tr); len(fs) > 0 {
+		return fmt.Errorf("the result is not valid, %s is left untouched: %w", p, &ValidationError{Findings: fs})
+	}
+
+	if dryRun {
+		return printProto(tr, out)
+	}
+	b, err = marshalCanonical(tr, out)
+	if err != nil {
+		return err
+	}
+	if err = writeFileAtomic(p, b); err != nil {
+		return err
+	}
+	if verbose {
+		fmt.Fprintf(w, "Applied %d changes to %s\n", len(changes), p)
+	}
+
+	return nil
+}
+
+func parseChanges(b []byte) ([]Change, error) {
+	var changes []Change
+
+	dec := yaml.NewDecoder(bytes.NewReader(b))
+	dec.KnownFields(true)
+	if err := dec.Decode(&changes); err != nil {
+		return nil, err
+	}
+
+	return changes, nil
+}
+
+func applyChange(tr *ptr.TrustedRoot, c Change, dir string, verbose bool) error {
+	field, err := entryField(c.Type)
+	if err != nil {
+		return err
+	}
+	if c.Op != OpAdd && c.URI == "" {
+		return errors.New("uri is required")
+	}
+	if c.Op != OpRotate && c.PrevEnd != "" {
+		return errors.New("prevEnd is only used by rotate")
+	}
+
+	switch c.Op {
+	case OpAdd:
+		return appendEntry(tr, c.Type, c.ManifestEntry, dir, verbose)
+	case OpRotate:
+		if err = appendEntry(tr, c.Type, c.ManifestEntry, dir, verbose); err != nil {
+			return err
+		}
+		return closeEntries(tr, c.Type, c.URI, c.PrevEnd)
+	case OpRemove:
+		t, err := resolveEntry(tr, field, c.URI, c.Index)
+		if err != nil {
+			return err
+		}
+		l := t.parent.Mutable(t.fd).List()
+		for i := t.index; i < l.Len()-1; i++ {
+			l.Set(i, l.Get(i+1))
+		}
+		l.Truncate(l.Len() - 1)
+		return nil
+	case OpSetValidity:
+		if c.Start == "" && c.End == "" {
+			return errors.New("start or end is required")
+		}
+		t, err := resolveEntry(tr, field, c.URI, c.Index)
+		if err != nil {
+			return err
+		}
+		// Select the entry by its position, setting the start may
+		// change what the URI selector matches.
+		path := fmt.Sprintf("%s[%d].validFor", field, t.index)
+		if c.Type == TypeTLog || c.Type == TypeCTLog {
+			path = fmt.Sprintf("%s[%d].publicKey.validFor", field, t.index)
+		}
+		if c.Start != "" {
+			if err = setPath(tr, path+".start", c.Start); err != nil {
+				return err
+			}
+		}
+		if c.End != "" {
+			return setPath(tr, path+".end", c.End)
+		}
+		return nil
+	}
+
+	return fmt.Errorf("unknown op %q, expected add, rotate, remove or set-validity", c.Op)
+}
+
+// entryField returns the trusted root field of an entry type.
+func entryField(typ string) (string, error) {
+	switch typ {
+	case TypeCA:
+		return "certificateAuthorities", nil
+	case TypeTSA:
+		return "timestampAuthorities", nil
+	case TypeTLog:
+		return "tlogs", nil
+	case TypeCTLog:
+		return "ctlogs", nil
+	}
+
+	return "", fmt.Errorf("invalid type %q, expected ca, tsa, tlog or ctlog", typ)
+}
+
+// resolveEntry selects a single entry by URI, and index among the
+// entries with that URI. URIs are compared as is, they may contain
+// characters that are special in a path.
+func resolveEntry(tr *ptr.TrustedRoot, field, uri string, index *int) (*pathTarget, error) {
+	var m = tr.ProtoReflect()
+	var fd = findField(m.Descriptor(), field)
+	var l = m.Get(fd).List()
+	var matches []int
+
+	for i := 0; i < l.Len(); i++ {
+		switch e := l.Get(i).Message().Interface().(type) {
+		case *ptr.CertificateAuthority:
+			if e.GetUri() == uri {
+				matches = append(matches, i)
+			}
+		case *ptr.TransparencyLogInstance:
+			if e.GetBaseUrl() == uri {
+				matches = append(matches, i)
+			}
+		}
+	}
+
+	switch {
+	case len(matches) == 0:
+		return nil, fmt.Errorf("no entry in %s with URI %s", field, uri)
+	case index != nil && (*index < 0 || *index >= len(matches)):
+		return nil, fmt.Errorf("index %d out of range for %d entries in %s with URI %s",
+			*index, len(matches), field, uri)
+	case index != nil:
+		return &pathTarget{parent: m, fd: fd, index: matches[*index]}, nil
+	case len(matches) > 1:
+		return nil, fmt.Errorf("%d entries in %s with URI %s, add an index", len(matches), field, uri)
+	}
+
+	return &pathTarget{parent: m, fd: fd, index: matches[0]}, nil
+}
+
+// closeEntries ends the open entries with the URI, except the last
+// one which was just added, at prevEnd or the start of the last entry.
+func closeEntries(tr *ptr.TrustedRoot, typ, uri, prevEnd string) error {
+	var ranges []*pc.TimeRange
+	var uris []string
+
+	switch typ {
+	case TypeCA, TypeTSA:
+		cas := tr.CertificateAuthorities
+		if typ == TypeTSA {
+			cas = tr.TimestampAuthorities
+		}
+		for _, ca := range cas {
+			ranges = append(ranges, ca.GetValidFor())
+			uris = append(uris, ca.GetUri())
+		}
+	case TypeTLog, TypeCTLog:
+		tlogs := tr.Tlogs
+		if typ == TypeCTLog {
+			tlogs = tr.Ctlogs
+		}
+		for _, tl := range tlogs {
+			ranges = append(ranges, tl.GetPublicKey().GetValidFor())
+			uris = append(uris, tl.GetBaseUrl())
+		}
+	}
+
+	var end = ranges[len(ranges)-1].GetStart()
+	if prevEnd != "" {
+		t, err := time.Parse(time.RFC3339, prevEnd)
+		if err != nil {
+			return fmt.Errorf("invalid prevEnd %s: %w", prevEnd, err)
+		}
+		end = timestamppb.New(t)
+	}
+
+	var closed int
+	for i, r := range ranges[:len(ranges)-1] {
+		if uris[i] == uri && r != nil && r.End == nil {
+			r.End = end
+			closed++
+		}
+	}
+	if closed == 0 {
+		return fmt.Errorf("no open entry with URI %s to rotate", uri)
+	}
+
+	return nil
+}
+
+// checkValidity requires every validity period to end after it
+// starts.
+func checkValidity(tr *ptr.TrustedRoot) []Finding {
+	var findings []Finding
+
+	check := func(path string, r *pc.TimeRange) {
+		if r.GetStart() == nil {
+			findings = append(findings, Finding{path + ".start", "required field is missing"})
+			return
+		}
+		if r.GetEnd() != nil && !r.GetEnd().AsTime().After(r.GetStart().AsTime()) {
+			findings = append(findings, Finding{path, fmt.Sprintf("ends at %s, before it starts",
+				r.GetEnd().AsTime().Format(time.RFC3339))})
+		}
+	}
+	for i, ca := range tr.GetCertificateAuthorities() {
+		check(fmt.Sprintf("$.certificateAuthorities[%d].validFor", i), ca.GetValidFor())
+	}
+	for i, ca := range tr.GetTimestampAuthorities() {
+		check(fmt.Sprintf("$.timestampAuthorities[%d].validFor", i), ca.GetValidFor())
+	}
+	for i, tl := range tr.GetTlogs() {
+		check(fmt.Sprintf("$.tlogs[%d].publicKey.validFor", i), tl.GetPublicKey().GetValidFor())
+	}
+	for i, tl := range tr.GetCtlogs() {
+		check(fmt.Sprintf("$.ctlogs[%d].publicKey.validFor", i), tl.GetPublicKey().GetValidFor())
+	}
+
+	return findings
+}
+
+// writeFileAtomic replaces the file by renaming a temporary file in the
+// same directory, so readers never see a partial write.
+func writeFileAtomic(p string, b []byte) error {
+	fi, err := os.Stat(p)
+	if err != nil {
+		return err
+	}
+	f, err := os.CreateTemp(filepath.Dir(p), "."+filepath.Base(p)+".*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(f.Name())
+
+	if _, err = f.Write(b); err != nil {
+		f.Close()
+		return fmt.Errorf("could not write %s: %w", f.Name(), err)
+	}
+	if err = f.Close(); err != nil {
+		return err
+	}
+	if err = os.Chmod(f.Name(), fi.Mode().Perm()); err != nil {
+		return err
+	}
+
+	return os.Rename(f.Name(), p)
+}
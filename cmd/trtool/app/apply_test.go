@@ -0,0 +1,124 @@
+package app
+
+import (
+	"io"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	ptr "github.com/sigstore/protobuf-specs/gen/pb-go/trustroot/v1"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestApply(t *testing.T) {
+	var dir = t.TempDir()
+	var changes = filepath.Join(dir, "ops.yaml")
+	var out = OutputOptions{Format: FormatJSON, Indent: 2, Newline: true}
+
+	_, root := writeTestTrustedRoot(t, dir, &RootManifest{
+		Tlogs: []ManifestEntry{{URI: "https://rekor.test", PEM: "rekor.pkix.pem", Start: "2024-04-03T00:00:00Z"}},
+	})
+	dataDir, err := filepath.Abs(testData)
+	assert.Nil(t, err)
+
+	assert.Nil(t, os.WriteFile(changes, []byte(`
+- op: rotate
+  type: tlog
+  uri: https://rekor.test
+  pem: `+dataDir+`/rekor.pkcs1.pem
+  start: 2025-01-01T00:00:00Z
+- op: add
+  type: ctlog
+  uri: https://ct.test
+  pem: `+dataDir+`/rekor.pkix.pem
+  start: 2024-04-03T00:00:00Z
+- op: set-validity
+  type: ctlog
+  uri: https://ct.test
+  end: 2025-06-01T00:00:00Z
+`), 0644))
+	assert.Nil(t, ApplyCmd(io.Discard, root, changes, false, false, DefaultInputOptions, out))
+	tr, err := readTrustedRoot(root, DefaultInputOptions)
+	assert.Nil(t, err)
+	assert.Len(t, tr.GetTlogs(), 2)
+	assert.Equal(t, "2025-01-01T00:00:00Z", tr.GetTlogs()[0].GetPublicKey().GetValidFor().GetEnd().AsTime().Format(time.RFC3339))
+	assert.Nil(t, tr.GetTlogs()[1].GetPublicKey().GetValidFor().GetEnd())
+	assert.Equal(t, "2025-06-01T00:00:00Z", tr.GetCtlogs()[0].GetPublicKey().GetValidFor().GetEnd().AsTime().Format(time.RFC3339))
+
+	// A failing change leaves the file untouched
+	before, err := os.ReadFile(root)
+	assert.Nil(t, err)
+	assert.Nil(t, os.WriteFile(changes, []byte(`
+- op: remove
+  type: ctlog
+  uri: https://ct.test
+- op: remove
+  type: tlog
+  uri: https://rekor.test
+`), 0644))
+	err = ApplyCmd(io.Discard, root, changes, false, false, DefaultInputOptions, out)
+	assert.ErrorContains(t, err, "change 1 (remove tlog): 2 entries in tlogs with URI https://rekor.test, add an index")
+	after, err := os.ReadFile(root)
+	assert.Nil(t, err)
+	assert.Equal(t, before, after)
+
+	// An end before the start fails the verification
+	assert.Nil(t, os.WriteFile(changes, []byte(`
+- op: set-validity
+  type: tlog
+  uri: https://rekor.test
+  index: 1
+  end: 2024-01-01T00:00:00Z
+`), 0644))
+	err = ApplyCmd(io.Discard, root, changes, false, false, DefaultInputOptions, out)
+	assert.ErrorContains(t, err, "$.tlogs[1].publicKey.validFor: ends at 2024-01-01T00:00:00Z, before it starts")
+	after, err = os.ReadFile(root)
+	assert.Nil(t, err)
+	assert.Equal(t, before, after)
+
+	// Operators are not supported by v0.1
+	assert.Nil(t, os.WriteFile(changes, []byte(`
+- op: add
+  type: ctlog
+  uri: https://ct2.test
+  pem: `+dataDir+`/rekor.pkix.pem
+  start: 2024-04-03T00:00:00Z
+  operator: test.com
+`), 0644))
+	err = ApplyCmd(io.Discard, root, changes, false, false, DefaultInputOptions, out)
+	assert.ErrorContains(t, err, "ctlogs[1].operator: not supported by v0.1")
+	after, err = os.ReadFile(root)
+	assert.Nil(t, err)
+	assert.Equal(t, before, after)
+
+	// prevEnd is only used by rotate
+	assert.Nil(t, os.WriteFile(changes, []byte(`
+- op: set-validity
+  type: ctlog
+  uri: https://ct.test
+  end: 2025-07-01T00:00:00Z
+  prevEnd: 2025-06-01T00:00:00Z
+`), 0644))
+	err = ApplyCmd(io.Discard, root, changes, false, false, DefaultInputOptions, out)
+	assert.ErrorContains(t, err, "change 0 (set-validity ctlog): prevEnd is only used by rotate")
+}
+
+func TestResolveEntry(t *testing.T) {
+	var tr = ptr.TrustedRoot{
+		Tlogs: []*ptr.TransparencyLogInstance{
+			{BaseUrl: "https://rekor.test/[v1]"},
+			{BaseUrl: "https://rekor.test"},
+			{BaseUrl: "https://rekor.test/[v1]"},
+		},
+	}
+
+	one := 1
+	e, err := resolveEntry(&tr, "tlogs", "https://rekor.test/[v1]", &one)
+	assert.Nil(t, err)
+	assert.Equal(t, 2, e.index)
+	_, err = resolveEntry(&tr, "tlogs", "https://rekor.test/[v1]", nil)
+	assert.ErrorContains(t, err, "2 entries in tlogs with URI https://rekor.test/[v1], add an index")
+	_, err = resolveEntry(&tr, "tlogs", "https://rekor.test/v1", nil)
+	assert.ErrorContains(t, err, "no entry in tlogs with URI https://rekor.test/v1")
+}
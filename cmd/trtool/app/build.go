@@ -0,0 +1,329 @@
+package app
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/BurntSushi/toml"
+	"github.com/peterbourgon/ff/v3/ffcli"
+	ptr "github.com/sigstore/protobuf-specs/gen/pb-go/trustroot/v1"
+	"gopkg.in/yaml.v3"
+)
+
+// FormatTOML is only supported for build manifests.
+const FormatTOML = "toml"
+
+// Manifest declares the content of a trusted root and a signing
+// config, in YAML or TOML. Relative PEM paths are relative to the
+// manifest.
+type Manifest struct {
+	TrustedRoot   *RootManifest          `yaml:"trustedRoot" toml:"trustedRoot"`
+	SigningConfig *SigningConfigManifest `yaml:"signingConfig" toml:"signingConfig"`
+}
+
+type RootManifest struct {
+	Version                string          `yaml:"version" toml:"version"`
+	CertificateAuthorities []ManifestEntry `yaml:"certificateAuthorities" toml:"certificateAuthorities"`
+	TimestampAuthorities   []ManifestEntry `yaml:"timestampAuthorities" toml:"timestampAuthorities"`
+	Tlogs                  []ManifestEntry `yaml:"tlogs" toml:"tlogs"`
+	Ctlogs                 []ManifestEntry `yaml:"ctlogs" toml:"ctlogs"`
+}
+
+// ManifestEntry is a CA, TSA or log of the trusted root. Padding is
+// only used for logs with an RSA key.
+type ManifestEntry struct {
+	URI      string `yaml:"uri" toml:"uri"`
+	PEM      string `yaml:"pem" toml:"pem"`
+	Start    string `yaml:"start" toml:"start"`
+	End      string `yaml:"end" toml:"end"`
+	Operator string `yaml:"operator" toml:"operator"`
+	Padding  string `yaml:"padding" toml:"padding"`
+}
+
+// SigningConfigManifest lists the services with the syntax of sc-init.
+// Start and Operator are the defaults for services without them.
+type SigningConfigManifest struct {
+	Version      string   `yaml:"version" toml:"version"`
+	Start        string   `yaml:"start" toml:"start"`
+	Operator     string   `yaml:"operator" toml:"operator"`
+	CAs          []string `yaml:"ca" toml:"ca"`
+	OIDCs        []string `yaml:"oidc" toml:"oidc"`
+	Tlogs        []string `yaml:"tlogs" toml:"tlogs"`
+	TSAs         []string `yaml:"tsas" toml:"tsas"`
+	TlogSelector string   `yaml:"tlogSelector" toml:"tlogSelector"`
+	TlogCount    uint     `yaml:"tlogCount" toml:"tlogCount"`
+	TSASelector  string   `yaml:"tsaSelector" toml:"tsaSelector"`
+	TSACount     uint     `yaml:"tsaCount" toml:"tsaCount"`
+}
+
+func Build() *ffcli.Command {
+	var (
+		flagset  = flag.NewFlagSet("trtool build", flag.ExitOnError)
+		manifest = flagset.String("manifest", "", "Manifest to build from, TOML if it ends with .toml, else YAML")
+		root     = flagset.String("root", "", "File to write the trusted root to, required if the manifest has one")
+		sc       = flagset.String("sc", "", "File to write the signing config to, required if the manifest has one")
+		verbose  = flagset.Bool("v", false, "verbose mode")
+		out      = addOutputFlags(flagset)
+	)
+
+	return &ffcli.Command{
+		Name:       "build",
+		ShortUsage: "trtool build -manifest sigstore.yaml -root trusted_root.json -sc signing_config.json",
+		ShortHelp:  "Build a trusted root and signing config from a manifest",
+		LongHelp: `Build a trusted root and a signing config from a YAML or TOML
+manifest, see the README for the format. Entries are added in the listed
+order and all validity periods are taken from the manifest, so the same
+manifest always gives the same documents. Nothing is written unless both
+documents could be built and the trusted root verifies.`,
+		FlagSet: flagset,
+		Exec: func(ctx context.Context, args []string) error {
+			if *manifest == "" {
+				return flag.ErrHelp
+			}
+
+			return BuildCmd(*manifest, *root, *sc, *verbose, *out)
+		},
+	}
+}
+
+func BuildCmd(manifest, root, scPath string, verbose bool, out OutputOptions) error {
+	b, err := os.ReadFile(manifest)
+	if err != nil {
+		return fmt.Errorf("could not read manifest %s: %w", manifest, err)
+	}
+	m, err := parseManifest(b, manifestFormat(manifest))
+	if err != nil {
+		return fmt.Errorf("invalid manifest %s: %w", manifest, err)
+	}
+
+	if m.TrustedRoot != nil && root == "" {
+		return errors.New("manifest has a trusted root, no file given with -root")
+	}
+	if m.SigningConfig != nil && scPath == "" {
+		return errors.New("manifest has a signing config, no file given with -sc")
+	}
+
+	var docs []document
+	if m.TrustedRoot != nil {
+		tr, err := buildTrustedRoot(m.TrustedRoot, filepath.Dir(manifest), verbose)
+		if err != nil {
+			return err
+		}
+		var findings bytes.Buffer
+		if !VerifyTrustedRoot(&findings, tr, false) {
+			return fmt.Errorf("the trusted root does not verify, nothing is written:\n%s",
+				strings.TrimSpace(findings.String()))
+		}
+		docs = append(docs, document{root, tr})
+	}
+	if m.SigningConfig != nil {
+		sc, err := buildSigningConfig(m.SigningConfig)
+		if err != nil {
+			return err
+		}
+		docs = append(docs, document{scPath, sc})
+	}
+
+	return writeDocuments(docs, out)
+}
+
+// manifestFormat returns the format of the manifest from its file
+// extension.
+func manifestFormat(p string) string {
+	if strings.EqualFold(filepath.Ext(p), ".toml") {
+		return FormatTOML
+	}
+
+	return FormatYAML
+}
+
+// parseManifest decodes a YAML or TOML manifest. Unknown keys are
+// rejected, as they most likely are typos.
+func parseManifest(b []byte, format string) (*Manifest, error) {
+	var m Manifest
+
+	switch format {
+	case FormatTOML:
+		md, err := toml.Decode(string(b), &m)
+		if err != nil {
+			return nil, err
+		}
+		if keys := md.Undecoded(); len(keys) > 0 {
+			return nil, fmt.Errorf("unknown keys %v", keys)
+		}
+	case FormatYAML:
+		dec := yaml.NewDecoder(bytes.NewReader(b))
+		dec.KnownFields(true)
+		if err := dec.Decode(&m); err != nil {
+			return nil, err
+		}
+	default:
+		return nil, fmt.Errorf("unsupported manifest format %s", format)
+	}
+	if m.TrustedRoot == nil && m.SigningConfig == nil {
+		return nil, errors.New("no trustedRoot or signingConfig")
+	}
+
+	return &m, nil
+}
+
+func buildTrustedRoot(m *RootManifest, dir string, verbose bool) (*ptr.TrustedRoot, error) {
+	var version = m.Version
+	if version == "" {
+		version = DefaultTrustedRootVersion
+	}
+	v, err := lookupVersion(KindTrustedRoot, version)
+	if err != nil {
+		return nil, err
+	}
+
+	var tr = ptr.TrustedRoot{
+		MediaType: v.MediaType,
+	}
+	for _, list := range []struct {
+		name    string
+		typ     string
+		entries []ManifestEntry
+	}{
+		{"certificateAuthorities", TypeCA, m.CertificateAuthorities},
+		{"timestampAuthorities", TypeTSA, m.TimestampAuthorities},
+		{"tlogs", TypeTLog, m.Tlogs},
+		{"ctlogs", TypeCTLog, m.Ctlogs},
+	} {
+		for i, e := range list.entries {
+			if err = appendEntry(&tr, list.typ, e, dir, verbose); err != nil {
+				return nil, fmt.Errorf("%s[%d]: %w", list.name, i, err)
+			}
+		}
+	}
+	if v.Version == "0.1" {
+		if err = checkV01Fields(&tr); err != nil {
+			return nil, err
+		}
+	}
+
+	return &tr, nil
+}
+
+// appendEntry adds a CA, TSA or log to the trusted root. Relative PEM
+// paths are relative to dir.
+func appendEntry(tr *ptr.TrustedRoot, typ string, e ManifestEntry, dir string, verbose bool) error {
+	if e.PEM == "" || e.Start == "" {
+		return errors.New("pem and start are required")
+	}
+	pemFile := e.PEM
+	if !filepath.IsAbs(pemFile) {
+		pemFile = filepath.Join(dir, pemFile)
+	}
+
+	switch typ {
+	case TypeCA, TypeTSA:
+		if e.Padding != "" {
+			return errors.New("padding is only used for logs")
+		}
+		ca, err := newCertificateAuthority(pemFile, e.Start, e.End, e.URI, verbose)
+		if err != nil {
+			return err
+		}
+		ca.Operator = e.Operator
+		if typ == TypeCA {
+			tr.CertificateAuthorities = append(tr.CertificateAuthorities, ca)
+		} else {
+			tr.TimestampAuthorities = append(tr.TimestampAuthorities, ca)
+		}
+	case TypeTLog, TypeCTLog:
+		padding := orDefault(e.Padding, RSAPKCS1v15)
+		if padding != RSAPKCS1v15 && padding != RSAPSS {
+			return fmt.Errorf("invalid RSA padding %s", padding)
+		}
+		tl, err := newTLog(pemFile, e.Start, e.End, e.URI, padding, verbose)
+		if err != nil {
+			return err
+		}
+		tl.Operator = e.Operator
+		if typ == TypeTLog {
+			tr.Tlogs = append(tr.Tlogs, tl)
+		} else {
+			tr.Ctlogs = append(tr.Ctlogs, tl)
+		}
+	default:
+		return fmt.Errorf("invalid type %q, expected ca, tsa, tlog or ctlog", typ)
+	}
+
+	return nil
+}
+
+func buildSigningConfig(m *SigningConfigManifest) (*ptr.SigningConfig, error) {
+	var version = m.Version
+	if version == "" {
+		version = DefaultSigningConfigVersion
+	}
+	v, err := lookupVersion(KindSigningConfig, version)
+	if err != nil {
+		return nil, err
+	}
+
+	var sc = ptr.SigningConfig{
+		MediaType: v.MediaType,
+	}
+	if sc.CaUrls, err = parseServices(m.CAs); err != nil {
+		return nil, fmt.Errorf("invalid CA: %w", err)
+	}
+	if sc.OidcUrls, err = parseServices(m.OIDCs); err != nil {
+		return nil, fmt.Errorf("invalid OIDC provider: %w", err)
+	}
+	if sc.RekorTlogUrls, err = parseServices(m.Tlogs); err != nil {
+		return nil, fmt.Errorf("invalid transparency log: %w", err)
+	}
+	if sc.TsaUrls, err = parseServices(m.TSAs); err != nil {
+		return nil, fmt.Errorf("invalid timestamp authority: %w", err)
+	}
+	if len(sc.RekorTlogUrls) > 0 {
+		if sc.RekorTlogConfig, err = newServiceConfig(orDefault(m.TlogSelector, "ALL"), m.TlogCount); err != nil {
+			return nil, fmt.Errorf("invalid transparency log selector: %w", err)
+		}
+	}
+	if len(sc.TsaUrls) > 0 {
+		if sc.TsaConfig, err = newServiceConfig(orDefault(m.TSASelector, "ALL"), m.TSACount); err != nil {
+			return nil, fmt.Errorf("invalid timestamp authority selector: %w", err)
+		}
+	}
+
+	if isSigningConfigV01(&sc) {
+		// Fail now rather than when writing
+		if _, err = marshalSigningConfigV01(&sc); err != nil {
+			return nil, err
+		}
+		return &sc, nil
+	}
+	// Defaulting the start to now would not be reproducible
+	if m.Start == "" {
+		for _, svcs := range [][]*ptr.Service{sc.CaUrls, sc.OidcUrls, sc.RekorTlogUrls, sc.TsaUrls} {
+			for _, s := range svcs {
+				if s.GetValidFor().GetStart() == nil {
+					return nil, fmt.Errorf("%s: no start, set it with start= or the signingConfig start", s.GetUrl())
+				}
+			}
+		}
+	}
+	defaults := ServiceDefaults{Start: m.Start, Operator: m.Operator}
+	if err = defaults.applyAll(&sc); err != nil {
+		return nil, err
+	}
+
+	return &sc, nil
+}
+
+func orDefault(s, def string) string {
+	if s == "" {
+		return def
+	}
+
+	return s
+}
@@ -0,0 +1,98 @@
+package app
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestBuild(t *testing.T) {
+	var dir = t.TempDir()
+	var manifest = filepath.Join(dir, "sigstore.yaml")
+	var root = filepath.Join(dir, "trusted_root.json")
+	var sc = filepath.Join(dir, "signing_config.json")
+	var out = OutputOptions{Format: FormatJSON, Indent: 2, Newline: true}
+
+	testData, err := filepath.Abs("../../../test_data")
+	assert.Nil(t, err)
+	assert.Nil(t, os.WriteFile(manifest, []byte(`
+trustedRoot:
+  version: "0.2"
+  certificateAuthorities:
+    - uri: https://fulcio.test
+      pem: `+testData+`/fulcio-chain.pem
+      start: 2024-04-03T00:00:00Z
+      operator: test.com
+  tlogs:
+    - uri: https://rekor.test
+      pem: `+testData+`/rekor.pkcs1.pem
+      start: 2024-04-03T00:00:00Z
+      padding: pss
+      operator: test.com
+signingConfig:
+  start: 2024-04-03T00:00:00Z
+  operator: test.com
+  ca: [https://fulcio.test]
+  oidc: [https://oauth2.test]
+  tlogs: [https://rekor.test]
+`), 0644))
+
+	assert.Nil(t, BuildCmd(manifest, root, sc, false, out))
+	first, err := os.ReadFile(root)
+	assert.Nil(t, err)
+	assert.Nil(t, BuildCmd(manifest, root, sc, false, out))
+	second, err := os.ReadFile(root)
+	assert.Nil(t, err)
+	assert.Equal(t, first, second)
+	assert.ErrorContains(t, BuildCmd(manifest, "", sc, false, out), "no file given with -root")
+
+	tr, err := readTrustedRoot(root, DefaultInputOptions)
+	assert.Nil(t, err)
+	assert.Equal(t, "test.com", tr.GetTlogs()[0].GetOperator())
+	assert.Contains(t, tr.GetTlogs()[0].GetPublicKey().GetKeyDetails().String(), "RSA_PSS")
+	s, err := readSigningConfig(sc, DefaultInputOptions)
+	assert.Nil(t, err)
+	assert.Empty(t, verifySigningConfig(s, tr.GetCertificateAuthorities()[0].GetValidFor().GetStart().AsTime()))
+
+	// Unknown keys are typos, and a failure writes nothing
+	assert.Nil(t, os.WriteFile(manifest, []byte("signingConfig:\n  cas: [https://fulcio.test]\n"), 0644))
+	assert.ErrorContains(t, BuildCmd(manifest, root+".new", sc+".new", false, out), "field cas not found")
+	assert.NoFileExists(t, root+".new")
+
+	// TOML, times are strings. A trusted root that does not verify,
+	// here the CA ends after its certificates, writes nothing
+	var tomlManifest = filepath.Join(dir, "sigstore.toml")
+	assert.Nil(t, os.WriteFile(tomlManifest, []byte(`
+[trustedRoot]
+version = "0.2"
+
+[[trustedRoot.certificateAuthorities]]
+uri = "https://fulcio.test"
+pem = "`+testData+`/fulcio-chain.pem"
+start = "2024-04-03T00:00:00Z"
+end = "2099-01-01T00:00:00Z"
+operator = "test.com"
+`), 0644))
+	assert.ErrorContains(t, BuildCmd(tomlManifest, root+".new", "", false, out), "does not verify")
+	assert.NoFileExists(t, root+".new")
+
+	assert.Nil(t, os.WriteFile(tomlManifest, []byte(`
+[signingConfig]
+start = "2024-04-03T00:00:00Z"
+operator = "test.com"
+ca = ["https://fulcio.test"]
+oidc = ["https://oauth2.test"]
+tlogs = ["https://rekor.test"]
+`), 0644))
+	assert.Nil(t, BuildCmd(tomlManifest, "", sc+".new", false, out))
+	fromTOML, err := os.ReadFile(sc + ".new")
+	assert.Nil(t, err)
+	fromYAML, err := os.ReadFile(sc)
+	assert.Nil(t, err)
+	assert.Equal(t, fromYAML, fromTOML)
+
+	assert.Nil(t, os.WriteFile(tomlManifest, []byte("[signingConfig]\ncas = [\"https://fulcio.test\"]\n"), 0644))
+	assert.ErrorContains(t, BuildCmd(tomlManifest, "", sc+".new", false, out), "unknown keys [signingConfig.cas]")
+}
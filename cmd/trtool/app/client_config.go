@@ -0,0 +1,150 @@
+package app
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+
+	"github.com/peterbourgon/ff/v3/ffcli"
+	ptr "github.com/sigstore/protobuf-specs/gen/pb-go/trustroot/v1"
+	"google.golang.org/protobuf/proto"
+)
+
+func ClientConfig() *ffcli.Command {
+	var (
+		flagset = flag.NewFlagSet("trtool client-config", flag.ExitOnError)
+		root    = flagset.String("root", "", "Trusted root to include")
+		sc      = flagset.String("sc", "", "Signing config to include")
+		in      = addInputFlags(flagset)
+		out     = addOutputFlags(flagset)
+	)
+
+	return &ffcli.Command{
+		Name:       "client-config",
+		ShortUsage: "trtool client-config -root trusted_root.json -sc signing_config.json",
+		ShortHelp:  "Build a client trust config",
+		LongHelp: `Build a client trust config from a trusted root and a signing config,
+and print it. The signing config must be version 0.2 or later. Use split to
+get the two parts back.`,
+		FlagSet: flagset,
+		Exec: func(ctx context.Context, args []string) error {
+			if *root == "" || *sc == "" {
+				return flag.ErrHelp
+			}
+
+			return ClientConfigCmd(*root, *sc, *in, *out)
+		},
+	}
+}
+
+func Split() *ffcli.Command {
+	var (
+		flagset = flag.NewFlagSet("trtool split", flag.ExitOnError)
+		file    = flagset.String("f", "", "Client trust config to split")
+		root    = flagset.String("root", "", "File to write the trusted root to")
+		sc      = flagset.String("sc", "", "File to write the signing config to")
+		in      = addInputFlags(flagset)
+		out     = addOutputFlags(flagset)
+	)
+
+	return &ffcli.Command{
+		Name:       "split",
+		ShortUsage: "trtool split -f client_trust_config.json -root trusted_root.json -sc signing_config.json",
+		ShortHelp:  "Split a client trust config",
+		LongHelp:   "Split a client trust config into a trusted root and a signing config. Both output files must be given, existing files are overwritten",
+		FlagSet:    flagset,
+		Exec: func(ctx context.Context, args []string) error {
+			if *file == "" || *root == "" || *sc == "" {
+				return flag.ErrHelp
+			}
+
+			return SplitCmd(*file, *root, *sc, *in, *out)
+		},
+	}
+}
+
+func ClientConfigCmd(root, scPath string, in InputOptions, out OutputOptions) error {
+	tr, err := readTrustedRoot(root, in)
+	if err != nil {
+		return err
+	}
+	sc, err := readSigningConfig(scPath, in)
+	if err != nil {
+		return err
+	}
+
+	ctc, err := newClientTrustConfig(tr, sc)
+	if err != nil {
+		return err
+	}
+
+	return printProto(ctc, out)
+}
+
+func SplitCmd(p, root, scPath string, in InputOptions, out OutputOptions) error {
+	var ctc ptr.ClientTrustConfig
+
+	b, err := os.ReadFile(p)
+	if err != nil {
+		return fmt.Errorf("could not read client trust config %s: %w", p, err)
+	}
+	if err = unmarshalProto(b, &ctc, in); err != nil {
+		return fmt.Errorf("failed to unmarshal client trust config: %w", err)
+	}
+	if ctc.GetTrustedRoot() == nil || ctc.GetSigningConfig() == nil {
+		return errors.New("client trust config must have a trusted root and a signing config")
+	}
+
+	return writeDocuments([]document{
+		{root, ctc.GetTrustedRoot()},
+		{scPath, ctc.GetSigningConfig()},
+	}, out)
+}
+
+// document is a message to write to a file.
+type document struct {
+	path string
+	m    proto.Message
+}
+
+// writeDocuments marshals all documents before writing any, so an
+// encoding error does not leave a partial result behind.
+func writeDocuments(docs []document, out OutputOptions) error {
+	var encoded [][]byte
+
+	for _, d := range docs {
+		b, err := marshalCanonical(d.m, out)
+		if err != nil {
+			return err
+		}
+		encoded = append(encoded, b)
+	}
+	for i, d := range docs {
+		if err := os.WriteFile(d.path, encoded[i], 0644); err != nil {
+			return fmt.Errorf("could not write %s: %w", d.path, err)
+		}
+	}
+
+	return nil
+}
+
+// newClientTrustConfig embeds the trusted root and signing config.
+// Version 0.1 signing configs are not protobuf messages anymore, so
+// they can not be embedded.
+func newClientTrustConfig(tr *ptr.TrustedRoot, sc *ptr.SigningConfig) (*ptr.ClientTrustConfig, error) {
+	v, err := lookupVersion(KindClientTrustConfig, "0.1")
+	if err != nil {
+		return nil, err
+	}
+	if isSigningConfigV01(sc) {
+		return nil, errors.New("signing config v0.1 can not be embedded in a client trust config, use migrate -to 0.2")
+	}
+
+	return &ptr.ClientTrustConfig{
+		MediaType:     v.MediaType,
+		TrustedRoot:   tr,
+		SigningConfig: sc,
+	}, nil
+}
@@ -0,0 +1,39 @@
+package app
+
+import (
+	"testing"
+
+	ptr "github.com/sigstore/protobuf-specs/gen/pb-go/trustroot/v1"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestClientTrustConfig(t *testing.T) {
+	var tr = ptr.TrustedRoot{
+		MediaType: "application/vnd.dev.sigstore.trustedroot.v0.1+json",
+	}
+	var sc = ptr.SigningConfig{
+		MediaType: "application/vnd.dev.sigstore.signingconfig.v0.2+json",
+		CaUrls:    []*ptr.Service{{Url: "https://fulcio.test", MajorApiVersion: 1, Operator: "test.com"}},
+	}
+
+	ctc, err := newClientTrustConfig(&tr, &sc)
+	assert.Nil(t, err)
+	assert.Equal(t, "application/vnd.dev.sigstore.clienttrustconfig.v0.1+json", ctc.GetMediaType())
+
+	for _, format := range []string{FormatJSON, FormatProto} {
+		b, err := marshalCanonical(ctc, OutputOptions{Format: format})
+		assert.Nil(t, err)
+
+		in := InputOptions{Format: format}
+		gotTr, err := unmarshalTrustedRoot(b, in)
+		assert.Nil(t, err)
+		assert.Equal(t, tr.GetMediaType(), gotTr.GetMediaType())
+		gotSc, err := unmarshalSigningConfig(b, in)
+		assert.Nil(t, err)
+		assert.Equal(t, "https://fulcio.test", gotSc.GetCaUrls()[0].GetUrl())
+	}
+
+	sc.MediaType = "application/vnd.dev.sigstore.signingconfig.v0.1+json"
+	_, err = newClientTrustConfig(&tr, &sc)
+	assert.ErrorContains(t, err, "use migrate -to 0.2")
+}
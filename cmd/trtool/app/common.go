@@ -21,11 +21,18 @@ import (
 )
 
 func newCertificateAuthority(pem, startStr, endStr, url string, verbose bool) (*ptr.CertificateAuthority, error) {
-	start, err := time.Parse(time.RFC3339, startStr)
+	chain, err := loadChain(pem, verbose)
 	if err != nil {
 		return nil, err
 	}
-	chain, err := loadChain(pem, verbose)
+
+	return newCertificateAuthorityFromChain(chain, startStr, endStr, url)
+}
+
+// newCertificateAuthorityFromChain creates a CA from an ordered chain,
+// leaf first.
+func newCertificateAuthorityFromChain(chain []*x509.Certificate, startStr, endStr, url string) (*ptr.CertificateAuthority, error) {
+	start, err := time.Parse(time.RFC3339, startStr)
 	if err != nil {
 		return nil, err
 	}
@@ -82,11 +89,18 @@ func newCertificateAuthority(pem, startStr, endStr, url string, verbose bool) (*
 }
 
 func newTLog(pem, startStr, endStr, url, padding string, verbose bool) (*ptr.TransparencyLogInstance, error) {
-	start, err := time.Parse(time.RFC3339, startStr)
+	der, err := loadPubKey(pem, verbose)
 	if err != nil {
 		return nil, err
 	}
-	der, err := loadPubKey(pem, verbose)
+
+	return newTLogFromKey(der, startStr, endStr, url, padding)
+}
+
+// newTLogFromKey creates a log from the DER encoded
+// SubjectPublicKeyInfo of its key.
+func newTLogFromKey(der []byte, startStr, endStr, url, padding string) (*ptr.TransparencyLogInstance, error) {
+	start, err := time.Parse(time.RFC3339, startStr)
 	if err != nil {
 		return nil, err
 	}
@@ -175,15 +189,32 @@ func extractKeyDetails(der []byte, padding string) (pc.PublicKeyDetails, error)
 func loadChain(p string, verbose bool) ([]*x509.Certificate, error) {
 	var b []byte
 	var err error
-	var certs []*x509.Certificate
-	var rest []byte
-	var block *pem.Block
 
 	if b, err = os.ReadFile(p); err != nil {
 		return nil, fmt.Errorf("failed to load pem file: %w", err)
 
 	}
 
+	return parseChain(b, verbose)
+}
+
+// parseChain parses PEM encoded certificates and orders them leaf,
+// intermediate(*), root.
+func parseChain(b []byte, verbose bool) ([]*x509.Certificate, error) {
+	certs, err := parseCertificates(b, verbose)
+	if err != nil {
+		return nil, err
+	}
+
+	return orderCertChain(certs)
+}
+
+// parseCertificates parses PEM encoded certificates, in file order.
+func parseCertificates(b []byte, verbose bool) ([]*x509.Certificate, error) {
+	var certs []*x509.Certificate
+	var rest []byte
+	var block *pem.Block
+
 	for {
 		block, rest = pem.Decode(b)
 		if block == nil || len(block.Bytes) == 0 {
@@ -191,8 +222,7 @@ func loadChain(p string, verbose bool) ([]*x509.Certificate, error) {
 		}
 		c, err := x509.ParseCertificate(block.Bytes)
 		if err != nil {
-			fmt.Println("Invalid certificate found")
-			panic(err)
+			return nil, fmt.Errorf("invalid certificate: %w", err)
 		}
 		certs = append(certs, c)
 
@@ -209,7 +239,7 @@ func loadChain(p string, verbose bool) ([]*x509.Certificate, error) {
 		b = rest
 	}
 
-	return orderCertChain(certs)
+	return certs, nil
 }
 
 // loadPubKey loads a public key from a PEM file, and returns the DER
@@ -219,15 +249,22 @@ func loadChain(p string, verbose bool) ([]*x509.Certificate, error) {
 func loadPubKey(p string, verbose bool) ([]byte, error) {
 	var b []byte
 	var err error
-	var block *pem.Block
 
 	if b, err = os.ReadFile(p); err != nil {
 		return nil, fmt.Errorf("failed to load pem file: %w", err)
 
 	}
 
+	return parsePubKey(b)
+}
+
+// parsePubKey returns the DER encoded SubjectPublicKeyInfo of the
+// first PEM encoded key.
+func parsePubKey(b []byte) ([]byte, error) {
+	var block *pem.Block
+
 	block, _ = pem.Decode(b)
-	if len(block.Bytes) == 0 {
+	if block == nil || len(block.Bytes) == 0 {
 		return nil, errors.New("empty key file")
 	}
 
@@ -1,11 +1,43 @@
 package app
 
 import (
+	"os"
+	"path/filepath"
 	"testing"
 
+	ptr "github.com/sigstore/protobuf-specs/gen/pb-go/trustroot/v1"
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
 )
 
+// testData is the directory with the test certificates and keys.
+const testData = "../../../test_data"
+
+// buildTestTrustedRoot builds a trusted root from the manifest, PEM
+// paths are relative to the test data.
+func buildTestTrustedRoot(t *testing.T, m *RootManifest) *ptr.TrustedRoot {
+	t.Helper()
+
+	tr, err := buildTrustedRoot(m, testData, false)
+	require.NoError(t, err)
+
+	return tr
+}
+
+// writeTestTrustedRoot builds a trusted root from the manifest and
+// writes it in canonical form to trusted_root.json in dir.
+func writeTestTrustedRoot(t *testing.T, dir string, m *RootManifest) (*ptr.TrustedRoot, string) {
+	t.Helper()
+
+	tr := buildTestTrustedRoot(t, m)
+	b, err := marshalCanonical(tr, DefaultOutputOptions)
+	require.NoError(t, err)
+	p := filepath.Join(dir, "trusted_root.json")
+	require.NoError(t, os.WriteFile(p, b, 0644))
+
+	return tr, p
+}
+
 func TestLoadChain(t *testing.T) {
 	var p = "../../../test_data/tsa-chain.pem"
 
@@ -0,0 +1,166 @@
+package app
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"net/url"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/peterbourgon/ff/v3/ffcli"
+	pc "github.com/sigstore/protobuf-specs/gen/pb-go/common/v1"
+	ptr "github.com/sigstore/protobuf-specs/gen/pb-go/trustroot/v1"
+)
+
+func Consistency() *ffcli.Command {
+	var (
+		flagset = flag.NewFlagSet("trtool consistency", flag.ExitOnError)
+		root    = flagset.String("root", "", "Trusted root")
+		sc      = flagset.String("sc", "", "Signing config")
+		verbose = flagset.Bool("v", false, "verbose mode")
+		in      = addInputFlags(flagset)
+	)
+
+	return &ffcli.Command{
+		Name:       "consistency",
+		ShortUsage: "trtool consistency -root trusted_root.json -sc signing_config.json",
+		ShortHelp:  "Cross-check a signing config against a trusted root",
+		LongHelp: `Cross-check a signing config against a trusted root. Every currently
+valid CA, transparency log and TSA in the signing config must have a currently
+valid entry in the trusted root, or clients sign artifacts nobody can verify.
+URLs match if scheme and host are equal and one path is a prefix of the
+other. Currently valid trusted root entries that the signing config does not
+reference are reported as warnings.`,
+		FlagSet: flagset,
+		Exec: func(ctx context.Context, args []string) error {
+			if *root == "" || *sc == "" {
+				return flag.ErrHelp
+			}
+
+			return ConsistencyCmd(os.Stdout, *root, *sc, *in, *verbose)
+		},
+	}
+}
+
+func ConsistencyCmd(w io.Writer, rootPath, scPath string, in InputOptions, verbose bool) error {
+	tr, err := readTrustedRoot(rootPath, in)
+	if err != nil {
+		return err
+	}
+	sc, err := readSigningConfig(scPath, in)
+	if err != nil {
+		return err
+	}
+
+	errs, warnings := checkConsistency(tr, sc, time.Now())
+	for _, f := range warnings {
+		fmt.Fprintf(w, "WARNING: %s\n", f)
+	}
+	for _, f := range errs {
+		fmt.Fprintln(w, f)
+	}
+	if len(errs) > 0 {
+		return errors.New("signing config and trusted root are not consistent")
+	} else if verbose {
+		fmt.Fprintln(w, "Signing config and trusted root are consistent")
+	}
+
+	return nil
+}
+
+// rootEntry is a trusted root entry a signing config service can
+// refer to.
+type rootEntry struct {
+	path     string
+	url      string
+	validFor *pc.TimeRange
+}
+
+// checkConsistency returns the signing config services without a
+// valid trusted root entry, and as warnings the valid trusted root
+// entries not referenced by the signing config.
+func checkConsistency(tr *ptr.TrustedRoot, sc *ptr.SigningConfig, now time.Time) ([]Finding, []Finding) {
+	var errs, warnings []Finding
+
+	var cas, tlogs, tsas []rootEntry
+	for i, ca := range tr.GetCertificateAuthorities() {
+		cas = append(cas, rootEntry{fmt.Sprintf("trusted root $.certificateAuthorities[%d]", i), ca.GetUri(), ca.GetValidFor()})
+	}
+	for i, tl := range tr.GetTlogs() {
+		tlogs = append(tlogs, rootEntry{fmt.Sprintf("trusted root $.tlogs[%d]", i), tl.GetBaseUrl(), tl.GetPublicKey().GetValidFor()})
+	}
+	for i, ca := range tr.GetTimestampAuthorities() {
+		tsas = append(tsas, rootEntry{fmt.Sprintf("trusted root $.timestampAuthorities[%d]", i), ca.GetUri(), ca.GetValidFor()})
+	}
+
+	windows := !isSigningConfigV01(sc)
+	pairs := []struct {
+		path     string
+		services []*ptr.Service
+		entries  []rootEntry
+	}{
+		{"signing config $.caUrls", sc.GetCaUrls(), cas},
+		{"signing config $.rekorTlogUrls", sc.GetRekorTlogUrls(), tlogs},
+		{"signing config $.tsaUrls", sc.GetTsaUrls(), tsas},
+	}
+	for _, p := range pairs {
+		referenced := make([]bool, len(p.entries))
+		for i, s := range p.services {
+			// Services outside their window neither need nor
+			// reference an entry
+			if windows && !covers(s.GetValidFor(), now) {
+				continue
+			}
+			var found, active bool
+			for j, e := range p.entries {
+				if !sameService(s.GetUrl(), e.url) {
+					continue
+				}
+				referenced[j] = true
+				found = true
+				active = active || covers(e.validFor, now)
+			}
+			path := fmt.Sprintf("%s[%d]", p.path, i)
+			switch {
+			case !found:
+				errs = append(errs, Finding{path, fmt.Sprintf("no trusted root entry for %s", s.GetUrl())})
+			case !active:
+				errs = append(errs, Finding{path, fmt.Sprintf("no trusted root entry for %s is valid at %s",
+					s.GetUrl(), now.UTC().Format(time.RFC3339))})
+			}
+		}
+		for j, e := range p.entries {
+			if !referenced[j] && covers(e.validFor, now) {
+				warnings = append(warnings, Finding{e.path, fmt.Sprintf("%s is not referenced by the signing config", e.url)})
+			}
+		}
+	}
+
+	return errs, warnings
+}
+
+// sameService tells if two URLs refer to the same service: the scheme
+// and host are equal and one path is a prefix of the other, as the
+// trusted root often only has the base URL.
+func sameService(a, b string) bool {
+	ua, err := url.Parse(a)
+	if err != nil || ua.Host == "" {
+		return false
+	}
+	ub, err := url.Parse(b)
+	if err != nil || ub.Host == "" {
+		return false
+	}
+	if !strings.EqualFold(ua.Scheme, ub.Scheme) || !strings.EqualFold(ua.Host, ub.Host) {
+		return false
+	}
+
+	pa := strings.TrimSuffix(ua.Path, "/") + "/"
+	pb := strings.TrimSuffix(ub.Path, "/") + "/"
+
+	return strings.HasPrefix(pa, pb) || strings.HasPrefix(pb, pa)
+}
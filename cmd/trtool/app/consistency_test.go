@@ -0,0 +1,59 @@
+package app
+
+import (
+	"testing"
+	"time"
+
+	pc "github.com/sigstore/protobuf-specs/gen/pb-go/common/v1"
+	ptr "github.com/sigstore/protobuf-specs/gen/pb-go/trustroot/v1"
+	"github.com/stretchr/testify/assert"
+	"google.golang.org/protobuf/types/known/timestamppb"
+)
+
+func TestCheckConsistency(t *testing.T) {
+	var now = time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)
+	var since = &pc.TimeRange{Start: timestamppb.New(now.AddDate(-1, 0, 0))}
+	var expired = &pc.TimeRange{Start: since.Start, End: timestamppb.New(now.AddDate(0, -1, 0))}
+	var tr = ptr.TrustedRoot{
+		CertificateAuthorities: []*ptr.CertificateAuthority{
+			{Uri: "https://fulcio.test", ValidFor: since},
+		},
+		Tlogs: []*ptr.TransparencyLogInstance{
+			{BaseUrl: "https://rekor.test", PublicKey: &pc.PublicKey{ValidFor: expired}},
+			{BaseUrl: "https://rekor2.test", PublicKey: &pc.PublicKey{ValidFor: since}},
+		},
+		TimestampAuthorities: []*ptr.CertificateAuthority{
+			{Uri: "https://tsa.test", ValidFor: since},
+			{Uri: "https://tsa2.test", ValidFor: since},
+		},
+	}
+	var sc = ptr.SigningConfig{
+		MediaType: "application/vnd.dev.sigstore.signingconfig.v0.2+json",
+		CaUrls:    []*ptr.Service{{Url: "https://fulcio.test/", ValidFor: since}},
+		RekorTlogUrls: []*ptr.Service{
+			{Url: "https://rekor.test", ValidFor: since},
+			{Url: "https://rekor3.test", ValidFor: expired},
+		},
+		TsaUrls: []*ptr.Service{
+			{Url: "https://tsa.test/api/v1/timestamp", ValidFor: since},
+			// An expired service does not reference its entry
+			{Url: "https://tsa2.test", ValidFor: expired},
+		},
+	}
+
+	errs, warnings := checkConsistency(&tr, &sc, now)
+	assert.Equal(t, []Finding{
+		{"signing config $.rekorTlogUrls[0]", "no trusted root entry for https://rekor.test is valid at 2025-01-01T00:00:00Z"},
+	}, errs)
+	assert.Equal(t, []Finding{
+		{"trusted root $.tlogs[1]", "https://rekor2.test is not referenced by the signing config"},
+		{"trusted root $.timestampAuthorities[1]", "https://tsa2.test is not referenced by the signing config"},
+	}, warnings)
+}
+
+func TestSameService(t *testing.T) {
+	assert.True(t, sameService("https://tsa.test/api/v1/timestamp", "https://TSA.test"))
+	assert.True(t, sameService("https://rekor.test/", "https://rekor.test"))
+	assert.False(t, sameService("https://rekor.test/api", "https://rekor.test/apiv2"))
+	assert.False(t, sameService("http://rekor.test", "https://rekor.test"))
+}
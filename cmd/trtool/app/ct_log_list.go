@@ -0,0 +1,345 @@
+package app
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"net/url"
+	"os"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/peterbourgon/ff/v3/ffcli"
+	ptr "github.com/sigstore/protobuf-specs/gen/pb-go/trustroot/v1"
+)
+
+// CT log states, as in the v3 log list schema.
+const (
+	CTStatePending   = "pending"
+	CTStateQualified = "qualified"
+	CTStateUsable    = "usable"
+	CTStateReadOnly  = "readonly"
+	CTStateRetired   = "retired"
+	CTStateRejected  = "rejected"
+)
+
+// ctLogList is a CT log list in the v3 schema, as published by Chrome
+// and Apple.
+type ctLogList struct {
+	Version          string          `json:"version,omitempty"`
+	LogListTimestamp string          `json:"log_list_timestamp,omitempty"`
+	Operators        []ctLogOperator `json:"operators"`
+}
+
+type ctLogOperator struct {
+	Name      string   `json:"name"`
+	Email     []string `json:"email"`
+	Logs      []ctLog  `json:"logs"`
+	TiledLogs []ctLog  `json:"tiled_logs,omitempty"`
+}
+
+// ctLog is a log, or a tiled log which has submission and monitoring
+// URLs instead of url.
+type ctLog struct {
+	Description      string                `json:"description,omitempty"`
+	LogID            string                `json:"log_id"`
+	Key              string                `json:"key"`
+	URL              string                `json:"url,omitempty"`
+	SubmissionURL    string                `json:"submission_url,omitempty"`
+	MonitoringURL    string                `json:"monitoring_url,omitempty"`
+	MMD              int                   `json:"mmd"`
+	State            map[string]ctLogState `json:"state,omitempty"`
+	TemporalInterval *ctTemporalInterval   `json:"temporal_interval,omitempty"`
+}
+
+type ctLogState struct {
+	Timestamp time.Time `json:"timestamp"`
+}
+
+type ctTemporalInterval struct {
+	StartInclusive time.Time `json:"start_inclusive"`
+	EndExclusive   time.Time `json:"end_exclusive"`
+}
+
+func ImportCTLogList() *ffcli.Command {
+	var (
+		flagset  = flag.NewFlagSet("trtool import-ct-log-list", flag.ExitOnError)
+		tr       = flagset.String("f", "trusted_root.json", "Trusted root file to update")
+		list     = flagset.String("list", "log_list.json", "CT log list to import from")
+		operator = flagset.String("operator", "", "Only import the logs of this operator")
+		urls     = flagset.String("url", "", "Comma separated URLs of the logs to import. Defaults to all logs")
+		start    = flagset.String("start", "", "Validity start for all imported logs")
+		in       = addInputFlags(flagset)
+		out      = addOutputFlags(flagset)
+	)
+
+	return &ffcli.Command{
+		Name:       "import-ct-log-list",
+		ShortUsage: "trtool import-ct-log-list -f trusted_root.json -list log_list.json -url https://ctfe.example/test/",
+		ShortHelp:  "Add CT logs from a v3 CT log list",
+		LongHelp: `Add the logs, and tiled logs, of a v3 CT log list, the format Chrome and
+Apple publish, as ctlogs and print the result. The log_id of each log
+must match the SHA-256 of its key. A log starts at -start, or else the
+time it entered its state, and readonly and retired logs end when they
+entered their state, so -start is required for them. The temporal
+interval bounds the certificates a log accepts, not when it issues SCTs,
+so it is not used. Pending and rejected logs, and keys already in the
+trusted root, are skipped.`,
+		FlagSet: flagset,
+		Exec: func(ctx context.Context, args []string) error {
+			var selected []string
+			if *urls != "" {
+				selected = strings.Split(*urls, ",")
+			}
+
+			return ImportCTLogListCmd(os.Stderr, *tr, *list, *operator, selected, *start, *in, *out)
+		},
+	}
+}
+
+func ImportCTLogListCmd(w io.Writer, trp, listPath, operator string, urls []string, start string, in InputOptions, out OutputOptions) error {
+	tr, err := readTrustedRoot(trp, in)
+	if err != nil {
+		return err
+	}
+	b, err := os.ReadFile(listPath)
+	if err != nil {
+		return fmt.Errorf("could not read log list %s: %w", listPath, err)
+	}
+	var list ctLogList
+	if err = json.Unmarshal(b, &list); err != nil {
+		return fmt.Errorf("invalid log list %s: %w", listPath, err)
+	}
+
+	if err = importCTLogs(w, tr, &list, operator, urls, start); err != nil {
+		return err
+	}
+
+	return printProto(tr, out)
+}
+
+// importCTLogs adds the selected logs of the list to the ctlogs, in
+// list order.
+func importCTLogs(w io.Writer, tr *ptr.TrustedRoot, list *ctLogList, operator string, urls []string, start string) error {
+	var n int
+
+	for _, op := range list.Operators {
+		if operator != "" && op.Name != operator {
+			continue
+		}
+		for _, l := range slices.Concat(op.Logs, op.TiledLogs) {
+			u := l.baseURL()
+			if len(urls) > 0 && !slices.ContainsFunc(urls, func(s string) bool { return sameURL(s, u) }) {
+				continue
+			}
+			n++
+
+			state, ts := l.currentState()
+			if state == CTStatePending || state == CTStateRejected {
+				fmt.Fprintf(w, "Skipping %s, it is %s\n", u, state)
+				continue
+			}
+			der, err := base64.StdEncoding.DecodeString(l.Key)
+			if err != nil {
+				return fmt.Errorf("%s: invalid key: %w", u, err)
+			}
+			logID, err := base64.StdEncoding.DecodeString(l.LogID)
+			if err != nil {
+				return fmt.Errorf("%s: invalid log id: %w", u, err)
+			}
+			if s := sha256.Sum256(der); !bytes.Equal(s[:], logID) {
+				return fmt.Errorf("%s: log id %s does not match the key, expected %s",
+					u, l.LogID, base64.StdEncoding.EncodeToString(s[:]))
+			}
+			if slices.ContainsFunc(tr.Ctlogs, func(tl *ptr.TransparencyLogInstance) bool {
+				return bytes.Equal(tl.GetPublicKey().GetRawBytes(), der)
+			}) {
+				fmt.Fprintf(w, "Skipping %s, it is already in the trusted root\n", u)
+				continue
+			}
+
+			// The temporal interval bounds the certificates a log
+			// accepts, not when it issues SCTs, so only the state is
+			// used.
+			var s, e = start, ""
+			switch state {
+			case CTStateReadOnly, CTStateRetired:
+				if !ts.IsZero() {
+					e = ts.UTC().Format(time.RFC3339)
+				}
+			default:
+				if s == "" && !ts.IsZero() {
+					s = ts.UTC().Format(time.RFC3339)
+				}
+			}
+			if s == "" {
+				return fmt.Errorf("%s: no validity start, set it with -start", u)
+			}
+
+			// CT log keys are ECDSA or RSA with PKCS#1 v1.5
+			tl, err := newTLogFromKey(der, s, e, u, RSAPKCS1v15)
+			if err != nil {
+				return fmt.Errorf("%s: %w", u, err)
+			}
+			tr.Ctlogs = append(tr.Ctlogs, tl)
+		}
+	}
+	if n == 0 {
+		return errors.New("no matching logs in the log list")
+	}
+
+	return nil
+}
+
+// baseURL returns the URL of a log, or the submission URL of a tiled
+// log, where clients add certificates.
+func (l ctLog) baseURL() string {
+	if l.URL != "" {
+		return l.URL
+	}
+
+	return l.SubmissionURL
+}
+
+// currentState returns the state of the log and when it was entered.
+// A log list has a single state per log.
+func (l ctLog) currentState() (string, time.Time) {
+	for s, v := range l.State {
+		return s, v.Timestamp
+	}
+
+	return "", time.Time{}
+}
+
+// sameURL compares URLs ignoring a trailing slash.
+func sameURL(a, b string) bool {
+	return strings.TrimSuffix(a, "/") == strings.TrimSuffix(b, "/")
+}
+
+func ExportCTLogList() *ffcli.Command {
+	var (
+		flagset = flag.NewFlagSet("trtool export-ct-log-list", flag.ExitOnError)
+		file    = flagset.String("f", "trusted_root.json", "Trusted root to export from")
+		email   = flagset.String("email", "", "Comma separated contact emails for the operators")
+		mmd     = flagset.Int("mmd", 86400, "Maximum merge delay of the logs, in seconds")
+		tiled   = flagset.String("tiled", "", "Comma separated URLs of tiled logs, each optionally followed by =monitoring URL")
+		in      = addInputFlags(flagset)
+	)
+
+	return &ffcli.Command{
+		Name:       "export-ct-log-list",
+		ShortUsage: "trtool export-ct-log-list -f trusted_root.json -email ct@example.com",
+		ShortHelp:  "Export the CT logs to a v3 CT log list",
+		LongHelp: `Export the ctlogs of a trusted root, or the trusted root of a client
+trust config, to a v3 CT log list. Logs are grouped by their operator,
+or the host of their URL. Logs with an end in the past are retired, the
+others are usable since their start. The trusted root does not record
+temporal intervals, so none are written. It does not record if a log is
+tiled, the logs given with -tiled are exported as tiled logs, with their
+URL as submission URL and the URL after = as monitoring URL, which
+defaults to the submission URL.`,
+		FlagSet: flagset,
+		Exec: func(ctx context.Context, args []string) error {
+			var emails = []string{}
+			if *email != "" {
+				emails = strings.Split(*email, ",")
+			}
+			var tiledLogs = map[string]string{}
+			if *tiled != "" {
+				for _, t := range strings.Split(*tiled, ",") {
+					submission, monitoring, _ := strings.Cut(t, "=")
+					tiledLogs[submission] = orDefault(monitoring, submission)
+				}
+			}
+
+			return ExportCTLogListCmd(os.Stdout, *file, emails, *mmd, tiledLogs, time.Now(), *in)
+		},
+	}
+}
+
+func ExportCTLogListCmd(w io.Writer, p string, emails []string, mmd int, tiled map[string]string, now time.Time, in InputOptions) error {
+	b, err := os.ReadFile(p)
+	if err != nil {
+		return fmt.Errorf("could not read trusted root %s: %w", p, err)
+	}
+	tr, err := unmarshalTrustedRoot(b, in)
+	if err != nil {
+		return err
+	}
+	if len(tr.GetCtlogs()) == 0 {
+		return errors.New("no ctlogs to export")
+	}
+
+	list := exportCTLogs(tr, emails, mmd, tiled, now)
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+
+	return enc.Encode(list)
+}
+
+// exportCTLogs converts the ctlogs to a log list, with the operators
+// in order of their first log. Logs with a URL in tiled are tiled logs,
+// tiled maps the submission URL to the monitoring URL.
+func exportCTLogs(tr *ptr.TrustedRoot, emails []string, mmd int, tiled map[string]string, now time.Time) *ctLogList {
+	var list = ctLogList{
+		Version:          "1.0",
+		LogListTimestamp: now.UTC().Format(time.RFC3339),
+	}
+
+	for _, tl := range tr.GetCtlogs() {
+		name := tl.GetOperator()
+		if name == "" {
+			if u, err := url.Parse(tl.GetBaseUrl()); err == nil && u.Host != "" {
+				name = u.Hostname()
+			} else {
+				name = tl.GetBaseUrl()
+			}
+		}
+		i := slices.IndexFunc(list.Operators, func(op ctLogOperator) bool { return op.Name == name })
+		if i < 0 {
+			list.Operators = append(list.Operators, ctLogOperator{Name: name, Email: emails, Logs: []ctLog{}})
+			i = len(list.Operators) - 1
+		}
+
+		pk := tl.GetPublicKey()
+		state := map[string]ctLogState{
+			CTStateUsable: {Timestamp: pk.GetValidFor().GetStart().AsTime().UTC()},
+		}
+		if end := pk.GetValidFor().GetEnd(); end != nil && end.AsTime().Before(now) {
+			state = map[string]ctLogState{
+				CTStateRetired: {Timestamp: end.AsTime().UTC()},
+			}
+		}
+		l := ctLog{
+			Description: tl.GetBaseUrl(),
+			LogID:       base64.StdEncoding.EncodeToString(tl.GetLogId().GetKeyId()),
+			Key:         base64.StdEncoding.EncodeToString(pk.GetRawBytes()),
+			MMD:         mmd,
+			State:       state,
+		}
+
+		var monitoring string
+		for submission, m := range tiled {
+			if sameURL(submission, tl.GetBaseUrl()) {
+				monitoring = m
+			}
+		}
+		if monitoring == "" {
+			l.URL = tl.GetBaseUrl()
+			list.Operators[i].Logs = append(list.Operators[i].Logs, l)
+			continue
+		}
+		l.SubmissionURL = tl.GetBaseUrl()
+		l.MonitoringURL = monitoring
+		list.Operators[i].TiledLogs = append(list.Operators[i].TiledLogs, l)
+	}
+
+	return &list
+}
@@ -0,0 +1,87 @@
+package app
+
+import (
+	"encoding/json"
+	"io"
+	"testing"
+	"time"
+
+	ptr "github.com/sigstore/protobuf-specs/gen/pb-go/trustroot/v1"
+	"github.com/stretchr/testify/assert"
+	"google.golang.org/protobuf/proto"
+)
+
+func TestImportCTLogs(t *testing.T) {
+	var list ctLogList
+	var tr ptr.TrustedRoot
+
+	assert.Nil(t, json.Unmarshal([]byte(`{"operators": [
+  {"name": "Test", "email": [], "logs": [
+    {"log_id": "/TKbCUU9CPkeXPLkZSBMayyIieby0t5s3hpm/mWvTDU=",
+     "key": "MIIBIjANBgkqhkiG9w0BAQEFAAOCAQ8AMIIBCgKCAQEAyuEumAOUjCAEM2unKrmJohSqGzAH6+TsETWSPYsB98xDIO5zdL43LD/dpEXW9DnRdGYKnlDCLYyFYiR7/gToxmiZgprn45ZvNxQQDnwHuUdIVnfYvDV5nTSrqMW7WZ1bWckkw5P00BNVXLCWBW6KCGflcZODXd8Nrk8lWzl32iUbKh48WbumvfmcIBdrouXrJ/fzGV3OYLiIk9dMP6ux18cceJeeMyn2rTnSknOMQP95OsdOh0G22bSbQFtCnGeNW+TOXsA5q9w59V56/gqGZksOAqLcZu2IhLq33q8r6kh47t2kGcvBFi6QUuqzavT2zguEHdP7nQNCYzfioEo3zwIDAQAB",
+     "url": "https://ctfe.test/2024/", "mmd": 86400,
+     "state": {"retired": {"timestamp": "2025-01-01T00:00:00Z"}},
+     "temporal_interval": {"start_inclusive": "2024-01-01T00:00:00Z", "end_exclusive": "2025-01-01T00:00:00Z"}},
+    {"log_id": "AAAA", "key": "AAAA", "url": "https://ctfe.test/pending/", "mmd": 86400,
+     "state": {"pending": {"timestamp": "2025-01-01T00:00:00Z"}}}
+  ], "tiled_logs": [
+    {"log_id": "qZXdRyeTeTYx96iQC0SiRbJiF0xpVZYn9+Ny2J4ff60=",
+     "key": "MFkwEwYHKoZIzj0CAQYIKoZIzj0DAQcDQgAE86JgEtHiBMAPK3GnT0bkeT/SMXExSCoVBetBnWoNIoY6hqQXS9o/Ea3X4R6XxoVXJ0+UFy276GkKS+BwPJb9cg==",
+     "submission_url": "https://ct.test/2025h2/", "monitoring_url": "https://tiles.test/2025h2/", "mmd": 60,
+     "state": {"usable": {"timestamp": "2025-06-01T00:00:00Z"}},
+     "temporal_interval": {"start_inclusive": "2025-07-01T00:00:00Z", "end_exclusive": "2026-01-01T00:00:00Z"}}
+  ]}
+]}`), &list))
+
+	assert.ErrorContains(t, importCTLogs(io.Discard, &tr, &list, "Other", nil, ""), "no matching logs")
+	// The retired log has no start, its state is when it ended
+	assert.ErrorContains(t, importCTLogs(io.Discard, &tr, &list, "", nil, ""),
+		"https://ctfe.test/2024/: no validity start, set it with -start")
+
+	// The usable tiled log issues SCTs from its state, before its
+	// temporal interval starts
+	assert.Nil(t, importCTLogs(io.Discard, &tr, &list, "", []string{"https://ct.test/2025h2"}, ""))
+	assert.Len(t, tr.GetCtlogs(), 1)
+	vf := tr.GetCtlogs()[0].GetPublicKey().GetValidFor()
+	assert.Equal(t, time.Date(2025, 6, 1, 0, 0, 0, 0, time.UTC), vf.GetStart().AsTime())
+	assert.Nil(t, vf.GetEnd())
+
+	assert.Nil(t, importCTLogs(io.Discard, &tr, &list, "", []string{"https://ctfe.test/2024"}, "2023-06-01T00:00:00Z"))
+	assert.Len(t, tr.GetCtlogs(), 2)
+	assert.Equal(t, "https://ctfe.test/2024/", tr.GetCtlogs()[1].GetBaseUrl())
+	vf = tr.GetCtlogs()[1].GetPublicKey().GetValidFor()
+	assert.Equal(t, time.Date(2023, 6, 1, 0, 0, 0, 0, time.UTC), vf.GetStart().AsTime())
+	assert.Equal(t, time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC), vf.GetEnd().AsTime())
+
+	// The exported log list imports to the same logs
+	exported := exportCTLogs(&tr, []string{}, 86400,
+		map[string]string{"https://ct.test/2025h2": "https://tiles.test/2025h2/"},
+		time.Date(2025, 12, 1, 0, 0, 0, 0, time.UTC))
+	assert.Equal(t, "ct.test", exported.Operators[0].Name)
+	tiled := exported.Operators[0].TiledLogs[0]
+	assert.Empty(t, tiled.URL)
+	assert.Equal(t, "https://ct.test/2025h2/", tiled.SubmissionURL)
+	assert.Equal(t, "https://tiles.test/2025h2/", tiled.MonitoringURL)
+	assert.Equal(t, time.Date(2025, 6, 1, 0, 0, 0, 0, time.UTC), tiled.State[CTStateUsable].Timestamp)
+	assert.Nil(t, tiled.TemporalInterval)
+	log := exported.Operators[1].Logs[0]
+	assert.Equal(t, list.Operators[0].Logs[0].LogID, log.LogID)
+	assert.Equal(t, list.Operators[0].Logs[0].Key, log.Key)
+	assert.Equal(t, time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC), log.State[CTStateRetired].Timestamp)
+	assert.Nil(t, log.TemporalInterval)
+
+	var reimported ptr.TrustedRoot
+	assert.Nil(t, importCTLogs(io.Discard, &reimported, exported, "", []string{"https://ct.test/2025h2"}, ""))
+	assert.Nil(t, importCTLogs(io.Discard, &reimported, exported, "", nil, "2023-06-01T00:00:00Z"))
+	assert.Len(t, reimported.GetCtlogs(), 2)
+	for i, tl := range reimported.GetCtlogs() {
+		assert.Equal(t, tr.GetCtlogs()[i].GetBaseUrl(), tl.GetBaseUrl())
+		assert.True(t, proto.Equal(tr.GetCtlogs()[i].GetPublicKey().GetValidFor(), tl.GetPublicKey().GetValidFor()))
+	}
+
+	// The log id must match the key
+	tr = ptr.TrustedRoot{}
+	list.Operators[0].Logs[0].LogID = "AAAA"
+	assert.ErrorContains(t, importCTLogs(io.Discard, &tr, &list, "", []string{"https://ctfe.test/2024"}, ""),
+		"does not match the key")
+}
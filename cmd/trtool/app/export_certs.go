@@ -0,0 +1,313 @@
+package app
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha1"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/asn1"
+	"encoding/binary"
+	"encoding/hex"
+	"encoding/pem"
+	"flag"
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"time"
+	"unicode/utf16"
+	"unicode/utf8"
+
+	"github.com/peterbourgon/ff/v3/ffcli"
+	ptr "github.com/sigstore/protobuf-specs/gen/pb-go/trustroot/v1"
+)
+
+const (
+	CertFormatPEM     = "pem"
+	CertFormatDER     = "der"
+	CertFormatHashDir = "hashdir"
+)
+
+func ExportCerts() *ffcli.Command {
+	var (
+		flagset  = flag.NewFlagSet("trtool export-certs", flag.ExitOnError)
+		file     = flagset.String("f", "trusted_root.json", "Trusted root to export from")
+		cType    = flagset.String("type", TypeCA, "The type, ca or tsa")
+		activeAt = flagset.String("active-at", "", "Only export entries valid at this time, RFC 3339. Defaults to all entries")
+		roots    = flagset.Bool("roots", false, "Only export the root certificates")
+		format   = flagset.String("format", CertFormatPEM, "Output format, pem, der or hashdir")
+		dir      = flagset.String("dir", "", "Directory to write to, for der and hashdir")
+		in       = addInputFlags(flagset)
+	)
+
+	return &ffcli.Command{
+		Name:       "export-certs",
+		ShortUsage: "trtool export-certs -f trusted_root.json -type tsa -format hashdir -dir certs/",
+		ShortHelp:  "Export the certificates of the CAs or TSAs",
+		LongHelp: `Export the root and intermediate certificates of the CAs or TSAs of a
+trusted root, or the trusted root of a client trust config. Certificates
+shared by several entries are exported once.
+  pem      print a PEM bundle, e.g. the accepted roots of a CT log
+  der      write each certificate to <sha256 fingerprint>.der in -dir
+  hashdir  write each certificate as PEM to <subject hash>.<n> in -dir,
+           like c_rehash, for openssl -CApath`,
+		FlagSet: flagset,
+		Exec: func(ctx context.Context, args []string) error {
+			var at time.Time
+
+			if *cType != TypeCA && *cType != TypeTSA {
+				return fmt.Errorf("invalid type %q, expected ca or tsa: %w", *cType, flag.ErrHelp)
+			}
+			if *format != CertFormatPEM && *dir == "" {
+				return fmt.Errorf("no output directory provided: %w", flag.ErrHelp)
+			}
+			if *activeAt != "" {
+				var err error
+				if at, err = time.Parse(time.RFC3339, *activeAt); err != nil {
+					return fmt.Errorf("invalid time %s: %w", *activeAt, err)
+				}
+			}
+
+			return ExportCertsCmd(*file, *cType, at, *roots, *format, *dir, *in)
+		},
+	}
+}
+
+func ExportCertsCmd(p, cType string, at time.Time, rootsOnly bool, format, dir string, in InputOptions) error {
+	b, err := os.ReadFile(p)
+	if err != nil {
+		return fmt.Errorf("could not read trusted root %s: %w", p, err)
+	}
+	tr, err := unmarshalTrustedRoot(b, in)
+	if err != nil {
+		return err
+	}
+
+	selected, err := selectCerts(tr, cType, at, rootsOnly)
+	if err != nil {
+		return err
+	}
+	if len(selected) == 0 {
+		return fmt.Errorf("no %s certificates to export", cType)
+	}
+	var certs []*x509.Certificate
+	for _, s := range selected {
+		certs = append(certs, s.cert)
+	}
+
+	switch format {
+	case CertFormatPEM:
+		for _, c := range certs {
+			if err = pem.Encode(os.Stdout, &pem.Block{Type: "CERTIFICATE", Bytes: c.Raw}); err != nil {
+				return err
+			}
+		}
+		return nil
+	case CertFormatDER:
+		return writeCertFiles(dir, certs, func(c *x509.Certificate, _ map[string]int) (string, []byte, error) {
+			fp := sha256.Sum256(c.Raw)
+			return hex.EncodeToString(fp[:]) + ".der", c.Raw, nil
+		})
+	case CertFormatHashDir:
+		return writeCertFiles(dir, certs, func(c *x509.Certificate, seen map[string]int) (string, []byte, error) {
+			h, err := subjectHash(c.RawSubject)
+			if err != nil {
+				return "", nil, fmt.Errorf("%s: %w", c.Subject, err)
+			}
+			prefix := fmt.Sprintf("%08x", h)
+			name := fmt.Sprintf("%s.%d", prefix, seen[prefix])
+			seen[prefix]++
+			return name, pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: c.Raw}), nil
+		})
+	}
+
+	return fmt.Errorf("invalid format %q, expected pem, der or hashdir", format)
+}
+
+// entityCert is a certificate and the URI of the first CA or TSA it
+// was found in.
+type entityCert struct {
+	uri  string
+	cert *x509.Certificate
+}
+
+// selectCerts returns the distinct certificates of the CAs or TSAs,
+// in trusted root order. A zero at selects all entries.
+func selectCerts(tr *ptr.TrustedRoot, cType string, at time.Time, rootsOnly bool) ([]entityCert, error) {
+	var certs []entityCert
+	var cas = tr.GetCertificateAuthorities()
+
+	if cType == TypeTSA {
+		cas = tr.GetTimestampAuthorities()
+	}
+	for _, ca := range cas {
+		if !at.IsZero() && !covers(ca.GetValidFor(), at) {
+			continue
+		}
+		for _, raw := range ca.GetCertChain().GetCertificates() {
+			c, err := x509.ParseCertificate(raw.GetRawBytes())
+			if err != nil {
+				return nil, fmt.Errorf("%s: invalid certificate: %w", ca.GetUri(), err)
+			}
+			if rootsOnly && !isSelfSigned(c) {
+				continue
+			}
+			if !slices.ContainsFunc(certs, func(e entityCert) bool { return e.cert.Equal(c) }) {
+				certs = append(certs, entityCert{ca.GetUri(), c})
+			}
+		}
+	}
+
+	return certs, nil
+}
+
+func isSelfSigned(c *x509.Certificate) bool {
+	return bytes.Equal(c.RawSubject, c.RawIssuer) && c.CheckSignatureFrom(c) == nil
+}
+
+// writeCertFiles writes each certificate to the file named by name,
+// which gets a map to count name collisions with.
+func writeCertFiles(dir string, certs []*x509.Certificate,
+	name func(*x509.Certificate, map[string]int) (string, []byte, error)) error {
+	var seen = map[string]int{}
+
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return err
+	}
+	for _, c := range certs {
+		n, b, err := name(c, seen)
+		if err != nil {
+			return err
+		}
+		if err = os.WriteFile(filepath.Join(dir, n), b, 0644); err != nil {
+			return fmt.Errorf("could not write %s: %w", n, err)
+		}
+	}
+
+	return nil
+}
+
+// canonATV and canonRDNSET mirror an X.509 name, keeping the raw
+// attribute values.
+type canonATV struct {
+	Type  asn1.ObjectIdentifier
+	Value asn1.RawValue
+}
+
+type canonRDNSET []canonATV
+
+// subjectHash computes the OpenSSL subject name hash used by c_rehash:
+// the first four bytes, little endian, of the SHA-1 of the canonical
+// name encoding. The canonical encoding is the RDN sets without the
+// outer sequence, with string values as lower case UTF8Strings with
+// the white space trimmed and collapsed.
+func subjectHash(rawName []byte) (uint32, error) {
+	var name []canonRDNSET
+	var canon []byte
+
+	rest, err := asn1.Unmarshal(rawName, &name)
+	if err != nil {
+		return 0, fmt.Errorf("invalid name: %w", err)
+	}
+	if len(rest) > 0 {
+		return 0, fmt.Errorf("trailing data after name")
+	}
+
+	for _, rdn := range name {
+		var atvs [][]byte
+		for _, atv := range rdn {
+			if s, ok := canonString(atv.Value); ok {
+				atv.Value = asn1.RawValue{Tag: asn1.TagUTF8String, Bytes: []byte(s)}
+			} else {
+				atv.Value = asn1.RawValue{FullBytes: atv.Value.FullBytes}
+			}
+			b, err := asn1.Marshal(atv)
+			if err != nil {
+				return 0, err
+			}
+			atvs = append(atvs, b)
+		}
+		// DER sorts the elements of a SET OF by their encoding
+		slices.SortFunc(atvs, bytes.Compare)
+		set, err := asn1.Marshal(asn1.RawValue{Class: asn1.ClassUniversal, Tag: asn1.TagSet,
+			IsCompound: true, Bytes: bytes.Join(atvs, nil)})
+		if err != nil {
+			return 0, err
+		}
+		canon = append(canon, set...)
+	}
+
+	sum := sha1.Sum(canon)
+
+	return binary.LittleEndian.Uint32(sum[:4]), nil
+}
+
+// canonString returns the canonical form of a string value, or false
+// if the value is not a string type OpenSSL canonicalizes.
+func canonString(v asn1.RawValue) (string, bool) {
+	var s string
+
+	if v.Class != asn1.ClassUniversal {
+		return "", false
+	}
+	switch v.Tag {
+	case asn1.TagUTF8String:
+		if !utf8.Valid(v.Bytes) {
+			return "", false
+		}
+		s = string(v.Bytes)
+	case asn1.TagPrintableString, asn1.TagIA5String, asn1.TagT61String, 26: // VisibleString
+		// One character per byte
+		var r = make([]rune, len(v.Bytes))
+		for i, b := range v.Bytes {
+			r[i] = rune(b)
+		}
+		s = string(r)
+	case asn1.TagBMPString:
+		if len(v.Bytes)%2 != 0 {
+			return "", false
+		}
+		var u = make([]uint16, len(v.Bytes)/2)
+		for i := range u {
+			u[i] = binary.BigEndian.Uint16(v.Bytes[2*i:])
+		}
+		s = string(utf16.Decode(u))
+	case 28: // UniversalString
+		if len(v.Bytes)%4 != 0 {
+			return "", false
+		}
+		var r = make([]rune, len(v.Bytes)/4)
+		for i := range r {
+			r[i] = rune(binary.BigEndian.Uint32(v.Bytes[4*i:]))
+		}
+		s = string(r)
+	default:
+		return "", false
+	}
+
+	// Only ASCII is lower cased and white space collapsed
+	var b strings.Builder
+	var space bool
+	for _, c := range []byte(strings.Trim(s, " \t\n\v\f\r")) {
+		switch {
+		case c >= 0x80:
+			b.WriteByte(c)
+			space = false
+		case strings.IndexByte(" \t\n\v\f\r", c) >= 0:
+			if !space {
+				b.WriteByte(' ')
+			}
+			space = true
+		default:
+			if 'A' <= c && c <= 'Z' {
+				c += 'a' - 'A'
+			}
+			b.WriteByte(c)
+			space = false
+		}
+	}
+
+	return b.String(), true
+}
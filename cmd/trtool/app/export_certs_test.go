@@ -0,0 +1,57 @@
+package app
+
+import (
+	"crypto/x509"
+	"encoding/asn1"
+	"encoding/pem"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSubjectHash(t *testing.T) {
+	// From openssl x509 -subject_hash
+	for f, want := range map[string]uint32{
+		"ca-root.crt":          0x3e26e6f5,
+		"intermediate-tsa.crt": 0xda61b4fb,
+		"leaf-tsa.crt":         0xb8ef1f7a,
+	} {
+		b, err := os.ReadFile("../../../test_data/" + f)
+		assert.Nil(t, err)
+		block, _ := pem.Decode(b)
+		c, err := x509.ParseCertificate(block.Bytes)
+		assert.Nil(t, err)
+		h, err := subjectHash(c.RawSubject)
+		assert.Nil(t, err)
+		assert.Equal(t, want, h, f)
+	}
+
+	s, ok := canonString(asn1.RawValue{Tag: asn1.TagPrintableString, Bytes: []byte("  Umbrella   Corp\t ")})
+	assert.True(t, ok)
+	assert.Equal(t, "umbrella corp", s)
+}
+
+func TestSelectCerts(t *testing.T) {
+	tr := buildTestTrustedRoot(t, &RootManifest{
+		CertificateAuthorities: []ManifestEntry{
+			{URI: "https://fulcio.test", PEM: "fulcio-chain.pem", Start: "2024-04-03T00:00:00Z", End: "2024-06-01T00:00:00Z"},
+			{URI: "https://fulcio.test", PEM: "fulcio-chain.pem", Start: "2024-06-01T00:00:00Z"},
+		},
+	})
+
+	// The chains are the same, so each certificate is exported once
+	certs, err := selectCerts(tr, TypeCA, time.Time{}, false)
+	assert.Nil(t, err)
+	assert.Len(t, certs, len(tr.GetCertificateAuthorities()[0].GetCertChain().GetCertificates()))
+
+	certs, err = selectCerts(tr, TypeCA, time.Time{}, true)
+	assert.Nil(t, err)
+	assert.Len(t, certs, 1)
+	assert.Equal(t, "Root", certs[0].cert.Subject.CommonName)
+
+	certs, err = selectCerts(tr, TypeCA, time.Date(2020, 1, 1, 0, 0, 0, 0, time.UTC), false)
+	assert.Nil(t, err)
+	assert.Empty(t, certs)
+}
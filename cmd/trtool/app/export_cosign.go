@@ -0,0 +1,161 @@
+package app
+
+import (
+	"bytes"
+	"context"
+	"encoding/pem"
+	"flag"
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/peterbourgon/ff/v3/ffcli"
+	ptr "github.com/sigstore/protobuf-specs/gen/pb-go/trustroot/v1"
+)
+
+// cosignFile is a legacy file cosign reads, and the environment
+// variable pointing to it.
+type cosignFile struct {
+	name string
+	env  string
+	pem  []byte
+}
+
+func ExportCosign() *ffcli.Command {
+	var (
+		flagset = flag.NewFlagSet("trtool export-cosign", flag.ExitOnError)
+		file    = flagset.String("f", "trusted_root.json", "Trusted root to export")
+		dir     = flagset.String("dir", "", "Directory to write the files to")
+		at      = flagset.String("at", "", "Export the entries valid at this time, RFC 3339. Defaults to now")
+		in      = addInputFlags(flagset)
+	)
+
+	return &ffcli.Command{
+		Name:       "export-cosign",
+		ShortUsage: "trtool export-cosign -f trusted_root.json -dir out/",
+		ShortHelp:  "Export a trusted root to legacy cosign files",
+		LongHelp: `Export the currently valid entries of a trusted root, or the trusted
+root of a client trust config, to the files older cosign versions read:
+  fulcio.crt.pem  the CA certificate chains  SIGSTORE_ROOT_FILE
+  rekor.pub       the tlog keys              SIGSTORE_REKOR_PUBLIC_KEY
+  ctfe.pub        the ctlog keys             SIGSTORE_CT_LOG_PUBLIC_KEY_FILE
+  tsa.crt.pem     the newest TSA chain       SIGSTORE_TSA_CERTIFICATE_FILE
+and cosign.env setting the variables to the absolute file paths. Files
+without entries are not written. Keys are written newest first, as some
+cosign versions only read the first key of a file.`,
+		FlagSet: flagset,
+		Exec: func(ctx context.Context, args []string) error {
+			if *dir == "" {
+				return fmt.Errorf("no output directory provided: %w", flag.ErrHelp)
+			}
+
+			var now = time.Now()
+			if *at != "" {
+				var err error
+				if now, err = time.Parse(time.RFC3339, *at); err != nil {
+					return fmt.Errorf("invalid time %s: %w", *at, err)
+				}
+			}
+
+			return ExportCosignCmd(*file, *dir, now, *in)
+		},
+	}
+}
+
+func ExportCosignCmd(p, dir string, now time.Time, in InputOptions) error {
+	b, err := os.ReadFile(p)
+	if err != nil {
+		return fmt.Errorf("could not read trusted root %s: %w", p, err)
+	}
+	tr, err := unmarshalTrustedRoot(b, in)
+	if err != nil {
+		return err
+	}
+
+	files := cosignFiles(tr, now)
+	if len(files) == 0 {
+		return fmt.Errorf("no entries valid at %s", now.UTC().Format(time.RFC3339))
+	}
+	abs, err := filepath.Abs(dir)
+	if err != nil {
+		return err
+	}
+	if err = os.MkdirAll(abs, 0755); err != nil {
+		return err
+	}
+
+	var env bytes.Buffer
+	for _, f := range files {
+		path := filepath.Join(abs, f.name)
+		if err = os.WriteFile(path, f.pem, 0644); err != nil {
+			return fmt.Errorf("could not write %s: %w", path, err)
+		}
+		fmt.Fprintf(&env, "%s=%s\n", f.env, path)
+	}
+
+	path := filepath.Join(abs, "cosign.env")
+	if err = os.WriteFile(path, env.Bytes(), 0644); err != nil {
+		return fmt.Errorf("could not write %s: %w", path, err)
+	}
+
+	return nil
+}
+
+// cosignFiles returns the PEM files for the entries valid at now.
+func cosignFiles(tr *ptr.TrustedRoot, now time.Time) []cosignFile {
+	var files []cosignFile
+
+	var cas []byte
+	for _, ca := range tr.GetCertificateAuthorities() {
+		if covers(ca.GetValidFor(), now) {
+			cas = append(cas, chainPEM(ca)...)
+		}
+	}
+	if len(cas) > 0 {
+		files = append(files, cosignFile{"fulcio.crt.pem", "SIGSTORE_ROOT_FILE", cas})
+	}
+
+	for _, l := range []struct {
+		name string
+		env  string
+		logs []*ptr.TransparencyLogInstance
+	}{
+		{"rekor.pub", "SIGSTORE_REKOR_PUBLIC_KEY", tr.GetTlogs()},
+		{"ctfe.pub", "SIGSTORE_CT_LOG_PUBLIC_KEY_FILE", tr.GetCtlogs()},
+	} {
+		var keys []byte
+		// Newest first, the entries are ordered oldest to newest
+		for i := len(l.logs) - 1; i >= 0; i-- {
+			pk := l.logs[i].GetPublicKey()
+			if covers(pk.GetValidFor(), now) && len(pk.GetRawBytes()) > 0 {
+				keys = append(keys, pem.EncodeToMemory(&pem.Block{Type: "PUBLIC KEY", Bytes: pk.GetRawBytes()})...)
+			}
+		}
+		if len(keys) > 0 {
+			files = append(files, cosignFile{l.name, l.env, keys})
+		}
+	}
+
+	// A TSA chain file holds a single chain
+	tsas := tr.GetTimestampAuthorities()
+	for i := len(tsas) - 1; i >= 0; i-- {
+		if covers(tsas[i].GetValidFor(), now) {
+			files = append(files, cosignFile{"tsa.crt.pem", "SIGSTORE_TSA_CERTIFICATE_FILE", chainPEM(tsas[i])})
+			break
+		}
+	}
+
+	return files
+}
+
+// chainPEM encodes the certificate chain of a CA, leaf first.
+func chainPEM(ca *ptr.CertificateAuthority) []byte {
+	var b []byte
+
+	for _, c := range ca.GetCertChain().GetCertificates() {
+		b = append(b, pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: c.GetRawBytes()})...)
+	}
+
+	return b
+}
@@ -0,0 +1,39 @@
+package app
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestExportCosign(t *testing.T) {
+	var dir = t.TempDir()
+
+	tr, root := writeTestTrustedRoot(t, dir, &RootManifest{
+		CertificateAuthorities: []ManifestEntry{{URI: "https://fulcio.test", PEM: "fulcio-chain.pem", Start: "2024-04-03T00:00:00Z"}},
+		Tlogs: []ManifestEntry{
+			{URI: "https://rekor.test", PEM: "rekor.pkcs1.pem", Start: "2024-04-03T00:00:00Z", End: "2024-06-01T00:00:00Z"},
+			{URI: "https://rekor.test", PEM: "rekor.pkix.pem", Start: "2024-06-01T00:00:00Z"},
+		},
+	})
+
+	assert.Nil(t, ExportCosignCmd(root, dir, time.Date(2024, 7, 1, 0, 0, 0, 0, time.UTC), DefaultInputOptions))
+	env, err := os.ReadFile(filepath.Join(dir, "cosign.env"))
+	assert.Nil(t, err)
+	assert.Equal(t, "SIGSTORE_ROOT_FILE="+filepath.Join(dir, "fulcio.crt.pem")+"\n"+
+		"SIGSTORE_REKOR_PUBLIC_KEY="+filepath.Join(dir, "rekor.pub")+"\n", string(env))
+
+	// Only the key valid at the time is exported
+	key, err := loadPubKey(filepath.Join(dir, "rekor.pub"), false)
+	assert.Nil(t, err)
+	assert.Equal(t, tr.GetTlogs()[1].GetPublicKey().GetRawBytes(), key)
+	chain, err := loadChain(filepath.Join(dir, "fulcio.crt.pem"), false)
+	assert.Nil(t, err)
+	assert.Len(t, chain, len(tr.GetCertificateAuthorities()[0].GetCertChain().GetCertificates()))
+
+	assert.ErrorContains(t, ExportCosignCmd(root, dir, time.Date(2020, 1, 1, 0, 0, 0, 0, time.UTC), DefaultInputOptions),
+		"no entries valid at 2020-01-01T00:00:00Z")
+}
@@ -0,0 +1,164 @@
+package app
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"net/url"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/peterbourgon/ff/v3/ffcli"
+	"software.sslmate.com/src/go-pkcs12"
+)
+
+const TrustStorePKCS12 = "pkcs12"
+
+func ExportTrustStore() *ffcli.Command {
+	var (
+		flagset  = flag.NewFlagSet("trtool export-truststore", flag.ExitOnError)
+		file     = flagset.String("f", "trusted_root.json", "Trusted root to export from")
+		output   = flagset.String("o", "truststore.p12", "Trust store to write")
+		format   = flagset.String("format", TrustStorePKCS12, "Trust store format, only pkcs12 is supported")
+		password = flagset.String("password-file", "", "File with the trust store password")
+		cType    = flagset.String("type", "all", "The certificates to include, ca, tsa or all")
+		activeAt = flagset.String("active-at", "", "Only include entries valid at this time, RFC 3339. Defaults to all entries")
+		roots    = flagset.Bool("roots", false, "Only include the root certificates")
+		legacy   = flagset.Bool("legacy", false, "Use the legacy RC2 encryption for Java versions before 8u301")
+		in       = addInputFlags(flagset)
+	)
+
+	return &ffcli.Command{
+		Name:       "export-truststore",
+		ShortUsage: "trtool export-truststore -f trusted_root.json -password-file pw.txt -o truststore.p12",
+		ShortHelp:  "Export the CA and TSA certificates to a Java trust store",
+		LongHelp: `Export the certificates of the CAs and TSAs of a trusted root, or the
+trusted root of a client trust config, to a PKCS#12 trust store for Java.
+Certificates shared by several entries are included once. The aliases are
+derived from the host of the entity URI and the certificate subject, so
+they are stable across exports, e.g. fulcio.sigstore.dev-sigstore. The
+password is the first line of -password-file.`,
+		FlagSet: flagset,
+		Exec: func(ctx context.Context, args []string) error {
+			var at time.Time
+
+			if *format != TrustStorePKCS12 {
+				return fmt.Errorf("unsupported format %q, only pkcs12 is supported: %w", *format, flag.ErrHelp)
+			}
+			if *cType != TypeCA && *cType != TypeTSA && *cType != "all" {
+				return fmt.Errorf("invalid type %q, expected ca, tsa or all: %w", *cType, flag.ErrHelp)
+			}
+			if *password == "" {
+				return fmt.Errorf("no password file provided: %w", flag.ErrHelp)
+			}
+			if *activeAt != "" {
+				var err error
+				if at, err = time.Parse(time.RFC3339, *activeAt); err != nil {
+					return fmt.Errorf("invalid time %s: %w", *activeAt, err)
+				}
+			}
+
+			return ExportTrustStoreCmd(*file, *output, *password, *cType, at, *roots, *legacy, *in)
+		},
+	}
+}
+
+func ExportTrustStoreCmd(p, output, passwordFile, cType string, at time.Time, rootsOnly, legacy bool, in InputOptions) error {
+	b, err := os.ReadFile(passwordFile)
+	if err != nil {
+		return fmt.Errorf("could not read password file %s: %w", passwordFile, err)
+	}
+	password, _, _ := strings.Cut(string(b), "\n")
+	password = strings.TrimSuffix(password, "\r")
+	if password == "" {
+		return errors.New("empty trust store password")
+	}
+
+	if b, err = os.ReadFile(p); err != nil {
+		return fmt.Errorf("could not read trusted root %s: %w", p, err)
+	}
+	tr, err := unmarshalTrustedRoot(b, in)
+	if err != nil {
+		return err
+	}
+
+	var certs []entityCert
+	for _, t := range []string{TypeCA, TypeTSA} {
+		if cType != "all" && cType != t {
+			continue
+		}
+		selected, err := selectCerts(tr, t, at, rootsOnly)
+		if err != nil {
+			return err
+		}
+		for _, s := range selected {
+			var dup bool
+			for _, c := range certs {
+				dup = dup || c.cert.Equal(s.cert)
+			}
+			if !dup {
+				certs = append(certs, s)
+			}
+		}
+	}
+	if len(certs) == 0 {
+		return errors.New("no certificates to export")
+	}
+
+	var entries []pkcs12.TrustStoreEntry
+	var aliases = map[string]int{}
+	for _, c := range certs {
+		alias := trustStoreAlias(c)
+		aliases[alias]++
+		if n := aliases[alias]; n > 1 {
+			alias = fmt.Sprintf("%s-%d", alias, n)
+		}
+		entries = append(entries, pkcs12.TrustStoreEntry{Cert: c.cert, FriendlyName: alias})
+	}
+
+	var enc = pkcs12.Modern
+	if legacy {
+		enc = pkcs12.LegacyRC2
+	}
+	pfx, err := enc.EncodeTrustStoreEntries(entries, password)
+	if err != nil {
+		return fmt.Errorf("failed to encode trust store: %w", err)
+	}
+
+	return os.WriteFile(output, pfx, 0644)
+}
+
+// trustStoreAlias derives an alias from the host of the entity URI
+// and the common name, or organization, of the certificate.
+func trustStoreAlias(c entityCert) string {
+	var parts []string
+
+	if u, err := url.Parse(c.uri); err == nil && u.Host != "" {
+		parts = append(parts, u.Hostname())
+	} else if c.uri != "" {
+		parts = append(parts, c.uri)
+	}
+	name := c.cert.Subject.CommonName
+	if name == "" && len(c.cert.Subject.Organization) > 0 {
+		name = c.cert.Subject.Organization[0]
+	}
+	parts = append(parts, name)
+
+	// Keytool lower cases aliases, keep them to a safe set
+	var b strings.Builder
+	var dash bool
+	for _, r := range strings.ToLower(strings.Join(parts, "-")) {
+		switch {
+		case r >= 'a' && r <= 'z', r >= '0' && r <= '9', r == '.':
+			b.WriteRune(r)
+			dash = false
+		case !dash && b.Len() > 0:
+			b.WriteByte('-')
+			dash = true
+		}
+	}
+
+	return strings.TrimSuffix(b.String(), "-")
+}
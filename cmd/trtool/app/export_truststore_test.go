@@ -0,0 +1,40 @@
+package app
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"software.sslmate.com/src/go-pkcs12"
+)
+
+func TestExportTrustStore(t *testing.T) {
+	var dir = t.TempDir()
+	var pw = filepath.Join(dir, "password")
+	var out = filepath.Join(dir, "truststore.p12")
+
+	_, root := writeTestTrustedRoot(t, dir, &RootManifest{
+		CertificateAuthorities: []ManifestEntry{{URI: "https://fulcio.test", PEM: "fulcio-chain.pem", Start: "2024-04-03T00:00:00Z"}},
+		TimestampAuthorities:   []ManifestEntry{{URI: "https://tsa.test/api/v1/timestamp", PEM: "tsa-chain.pem", Start: "2024-04-03T00:00:00Z"}},
+	})
+	assert.Nil(t, os.WriteFile(pw, []byte("changeit\n"), 0600))
+
+	// Both chains share the root, which is included once
+	assert.Nil(t, ExportTrustStoreCmd(root, out, pw, "all", time.Time{}, true, false, DefaultInputOptions))
+	pfx, err := os.ReadFile(out)
+	assert.Nil(t, err)
+	certs, err := pkcs12.DecodeTrustStore(pfx, "changeit")
+	assert.Nil(t, err)
+	assert.Len(t, certs, 1)
+	assert.Equal(t, "fulcio.test-root", trustStoreAlias(entityCert{"https://fulcio.test", certs[0]}))
+
+	assert.Nil(t, ExportTrustStoreCmd(root, out, pw, TypeTSA, time.Time{}, false, false, DefaultInputOptions))
+	pfx, err = os.ReadFile(out)
+	assert.Nil(t, err)
+	certs, err = pkcs12.DecodeTrustStore(pfx, "changeit")
+	assert.Nil(t, err)
+	assert.Len(t, certs, 3)
+	assert.Equal(t, "tsa.test-tsa-timestamping", trustStoreAlias(entityCert{"https://tsa.test/api/v1/timestamp", certs[0]}))
+}
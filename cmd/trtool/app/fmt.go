@@ -0,0 +1,99 @@
+package app
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+
+	"github.com/peterbourgon/ff/v3/ffcli"
+)
+
+// ErrNotCanonical is returned when a file is not in canonical form.
+var ErrNotCanonical = errors.New("not canonically formatted")
+
+func Fmt() *ffcli.Command {
+	var (
+		flagset = flag.NewFlagSet("trtool fmt", flag.ExitOnError)
+		file    = flagset.String("f", "", "Trusted root or signing config to format")
+		check   = flagset.Bool("check", false, "Only check if the file is canonically formatted, do not rewrite it")
+		in      = addInputFlags(flagset)
+		out     = addOutputFlags(flagset)
+	)
+
+	return &ffcli.Command{
+		Name:       "fmt",
+		ShortUsage: "trtool fmt -f file.json",
+		ShortHelp:  "Rewrite a trusted root or signing config in canonical form",
+		LongHelp:   "Rewrite a trusted root or signing config in place in canonical form. With -check the file is left untouched and an error is returned if it is not canonical. The output format defaults to the input format, the format of a file can not be changed in place",
+		FlagSet:    flagset,
+		Exec: func(ctx context.Context, args []string) error {
+			if *file == "" {
+				return flag.ErrHelp
+			}
+			var outFormat bool
+			flagset.Visit(func(f *flag.Flag) {
+				outFormat = outFormat || f.Name == "out-format"
+			})
+			if !outFormat {
+				out.Format = in.Format
+			}
+
+			return FmtCmd(*file, *check, *in, *out)
+		},
+	}
+}
+
+func FmtCmd(p string, check bool, in InputOptions, out OutputOptions) error {
+	var b []byte
+	var err error
+
+	if formatOrJSON(in.Format) != formatOrJSON(out.Format) {
+		return fmt.Errorf("%s is rewritten in place, can not change format from %s to %s",
+			p, formatOrJSON(in.Format), formatOrJSON(out.Format))
+	}
+	if b, err = os.ReadFile(p); err != nil {
+		return err
+	}
+
+	canonical, err := formatCanonical(b, in, out)
+	if err != nil {
+		return fmt.Errorf("failed to format %s: %w", p, err)
+	}
+
+	if bytes.Equal(b, canonical) {
+		return nil
+	}
+	if check {
+		return fmt.Errorf("%s: %w", p, ErrNotCanonical)
+	}
+
+	fi, err := os.Stat(p)
+	if err != nil {
+		return err
+	}
+
+	return os.WriteFile(p, canonical, fi.Mode().Perm())
+}
+
+// formatCanonical parses a trusted root or signing config, based on
+// the media type, and returns the canonical serialization.
+func formatCanonical(b []byte, in InputOptions, out OutputOptions) ([]byte, error) {
+	m, err := unmarshalDocument(b, in)
+	if err != nil {
+		return nil, err
+	}
+
+	return marshalCanonical(m, out)
+}
+
+// formatOrJSON returns the format, where empty means JSON.
+func formatOrJSON(f string) string {
+	if f == "" {
+		return FormatJSON
+	}
+
+	return f
+}
@@ -0,0 +1,49 @@
+package app
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestFmt(t *testing.T) {
+	var p = filepath.Join(t.TempDir(), "tr.json")
+	var in = []byte(`{"mediaType":  "application/vnd.dev.sigstore.trustedroot+json;version=0.1",
+"certificateAuthorities": [{"uri": "https://ca.test", "validFor": {"start": "2024-04-03T00:00:00Z"}}]}`)
+
+	assert.Nil(t, os.WriteFile(p, in, 0600))
+
+	err := FmtCmd(p, true, DefaultInputOptions, DefaultOutputOptions)
+	assert.True(t, errors.Is(err, ErrNotCanonical))
+
+	assert.Nil(t, FmtCmd(p, false, DefaultInputOptions, DefaultOutputOptions))
+	out, err := os.ReadFile(p)
+	assert.Nil(t, err)
+	assert.Equal(t, `{
+  "mediaType": "application/vnd.dev.sigstore.trustedroot+json;version=0.1",
+  "certificateAuthorities": [
+    {
+      "uri": "https://ca.test",
+      "validFor": {
+        "start": "2024-04-03T00:00:00Z"
+      }
+    }
+  ]
+}
+`, string(out))
+	assert.Nil(t, FmtCmd(p, true, DefaultInputOptions, DefaultOutputOptions))
+
+	// A different indentation is not canonical
+	err = FmtCmd(p, true, DefaultInputOptions, OutputOptions{Format: FormatJSON, Newline: true})
+	assert.True(t, errors.Is(err, ErrNotCanonical))
+
+	// The format can not be changed in place
+	err = FmtCmd(p, false, InputOptions{Format: FormatYAML}, DefaultOutputOptions)
+	assert.NotNil(t, err)
+	b, err := os.ReadFile(p)
+	assert.Nil(t, err)
+	assert.Equal(t, out, b)
+}
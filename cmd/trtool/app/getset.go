@@ -0,0 +1,135 @@
+package app
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"os"
+
+	"github.com/peterbourgon/ff/v3/ffcli"
+	"google.golang.org/protobuf/proto"
+)
+
+func Get() *ffcli.Command {
+	var (
+		flagset = flag.NewFlagSet("trtool get", flag.ExitOnError)
+		file    = flagset.String("f", "trusted_root.json", "Trusted root or signing config to read from")
+		in      = addInputFlags(flagset)
+	)
+
+	return &ffcli.Command{
+		Name:       "get",
+		ShortUsage: "trtool get -f trusted_root.json 'tlogs[uri=https://rekor.example].publicKey.validFor.end'",
+		ShortHelp:  "Print a field of a trusted root",
+		LongHelp: `Print a field of a trusted root or signing config.
+Fields are named by their JSON or protobuf name and separated by dots.
+Elements of a list are selected by index, tlogs[0], or by the value of a
+field, tlogs[baseUrl=https://rekor.example]. uri can be used for baseUrl.
+Strings, enums, bytes and timestamps are printed as is, messages and lists
+as JSON.`,
+		FlagSet: flagset,
+		Exec: func(ctx context.Context, args []string) error {
+			if len(args) != 1 {
+				return fmt.Errorf("expected a single path: %w", flag.ErrHelp)
+			}
+
+			return GetCmd(*file, args[0], *in)
+		},
+	}
+}
+
+func Set() *ffcli.Command {
+	var (
+		flagset = flag.NewFlagSet("trtool set", flag.ExitOnError)
+		file    = flagset.String("f", "trusted_root.json", "Trusted root or signing config to update")
+		in      = addInputFlags(flagset)
+		out     = addOutputFlags(flagset)
+	)
+
+	return &ffcli.Command{
+		Name:       "set",
+		ShortUsage: "trtool set -f trusted_root.json 'tlogs[uri=https://rekor.example].publicKey.validFor.end' 2024-12-31T00:00:00Z",
+		ShortHelp:  "Update a field of a trusted root",
+		LongHelp: `Update a field of a trusted root or signing config and print the result.
+The path is the same as for get. The value is validated against the field's
+type: timestamps must be RFC 3339, enums must be a valid name, bytes must be
+base64 encoded and messages and lists must be JSON.`,
+		FlagSet: flagset,
+		Exec: func(ctx context.Context, args []string) error {
+			if len(args) != 2 {
+				return fmt.Errorf("expected a path and a value: %w", flag.ErrHelp)
+			}
+
+			return SetCmd(*file, args[0], args[1], *in, *out)
+		},
+	}
+}
+
+func GetCmd(p, path string, in InputOptions) error {
+	m, err := readDocument(p, in)
+	if err != nil {
+		return err
+	}
+
+	v, err := getPath(m, path)
+	if err != nil {
+		return err
+	}
+
+	fmt.Println(v)
+
+	return nil
+}
+
+func SetCmd(p, path, value string, in InputOptions, out OutputOptions) error {
+	m, err := readDocument(p, in)
+	if err != nil {
+		return err
+	}
+
+	if err = setPath(m, path, value); err != nil {
+		return err
+	}
+
+	return printProto(m, out)
+}
+
+func getPath(m proto.Message, path string) (string, error) {
+	segs, err := parsePath(path)
+	if err != nil {
+		return "", fmt.Errorf("invalid path %s: %w", path, err)
+	}
+	t, err := resolvePath(m.ProtoReflect(), segs, false)
+	if err != nil {
+		return "", err
+	}
+
+	return t.format()
+}
+
+func setPath(m proto.Message, path, value string) error {
+	segs, err := parsePath(path)
+	if err != nil {
+		return fmt.Errorf("invalid path %s: %w", path, err)
+	}
+	t, err := resolvePath(m.ProtoReflect(), segs, true)
+	if err != nil {
+		return err
+	}
+
+	return t.set(value)
+}
+
+func readDocument(p string, in InputOptions) (proto.Message, error) {
+	b, err := os.ReadFile(p)
+	if err != nil {
+		return nil, fmt.Errorf("could not read %s: %w", p, err)
+	}
+
+	m, err := unmarshalDocument(b, in)
+	if err != nil {
+		return nil, fmt.Errorf("failed to unmarshal %s: %w", p, err)
+	}
+
+	return m, nil
+}
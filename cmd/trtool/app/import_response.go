@@ -0,0 +1,223 @@
+package app
+
+import (
+	"bytes"
+	"context"
+	"crypto/x509"
+	"encoding/json"
+	"encoding/pem"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/peterbourgon/ff/v3/ffcli"
+	ptr "github.com/sigstore/protobuf-specs/gen/pb-go/trustroot/v1"
+)
+
+// fulcioTrustBundle is the response of Fulcio's /api/v2/trustBundle.
+type fulcioTrustBundle struct {
+	Chains []struct {
+		Certificates []string `json:"certificates"`
+	} `json:"chains"`
+}
+
+func ImportResponse() *ffcli.Command {
+	var (
+		flagset  = flag.NewFlagSet("trtool import-response", flag.ExitOnError)
+		tr       = flagset.String("f", "trusted_root.json", "Trusted root file to update")
+		nType    = flagset.String("type", "", "the type, ca, tsa, tlog or ctlog")
+		uri      = flagset.String("uri", "", "the uri of the service")
+		response = flagset.String("response", "", "Saved API response to import")
+		start    = flagset.String("start", "", "Validity start time. Defaults to the latest 'not before' of a chain, required for keys")
+		end      = flagset.String("end", "", "Validity end time")
+		padding  = flagset.String("padding", "pkcs1v15", "For RSA key, the padding scheme to use. PKCS#1 v1.5 is the default, pss is also supported")
+		in       = addInputFlags(flagset)
+		out      = addOutputFlags(flagset)
+	)
+
+	return &ffcli.Command{
+		Name:       "import-response",
+		ShortUsage: "trtool import-response -f trusted_root.json -type ca -uri https://fulcio.example -response trustBundle.json",
+		ShortHelp:  "Add entries from a saved API response",
+		LongHelp: `Add CAs, TSAs or logs from a saved API response and print the result.
+  ca, tsa      Fulcio's /api/v2/trustBundle JSON, where each chain becomes
+               an entry, or a PEM chain like the TSA's
+               /api/v1/timestamp/certchain
+  tlog, ctlog  PEM public keys like Rekor's /api/v1/log/publicKey, where
+               each key becomes an entry
+Chains and keys already in the trusted root are skipped. Existing entries
+are not closed, use add or apply to rotate.`,
+		FlagSet: flagset,
+		Exec: func(ctx context.Context, args []string) error {
+			if *nType != TypeCA && *nType != TypeTSA && *nType != TypeTLog && *nType != TypeCTLog {
+				return flag.ErrHelp
+			}
+			if *response == "" {
+				return fmt.Errorf("no response provided: %w", flag.ErrHelp)
+			}
+			if *padding != RSAPKCS1v15 && *padding != RSAPSS {
+				return fmt.Errorf("invalid RSA padding: %w", flag.ErrHelp)
+			}
+
+			return ImportResponseCmd(os.Stderr, *tr, *nType, *uri, *response, *start, *end, *padding, *in, *out)
+		},
+	}
+}
+
+func ImportResponseCmd(w io.Writer, trp, nType, uri, response, start, end, padding string, in InputOptions, out OutputOptions) error {
+	tr, err := readTrustedRoot(trp, in)
+	if err != nil {
+		return err
+	}
+	b, err := os.ReadFile(response)
+	if err != nil {
+		return fmt.Errorf("could not read response %s: %w", response, err)
+	}
+
+	switch nType {
+	case TypeCA, TypeTSA:
+		err = importChains(w, tr, nType, uri, b, start, end)
+	default:
+		err = importKeys(w, tr, nType, uri, b, start, end, padding)
+	}
+	if err != nil {
+		return err
+	}
+
+	return printProto(tr, out)
+}
+
+// importChains adds each chain of a trust bundle, or a single PEM
+// chain, ordered by start.
+func importChains(w io.Writer, tr *ptr.TrustedRoot, caType, uri string, b []byte, start, end string) error {
+	chains, err := parseChains(b)
+	if err != nil {
+		return err
+	}
+
+	var cas = &tr.CertificateAuthorities
+	if caType == TypeTSA {
+		cas = &tr.TimestampAuthorities
+	}
+	var added []*ptr.CertificateAuthority
+	for i, chain := range chains {
+		if slices.ContainsFunc(*cas, func(ca *ptr.CertificateAuthority) bool { return sameChain(ca, chain) }) {
+			fmt.Fprintf(w, "Skipping chain %d of %s, it is already in the trusted root\n", i, chain[0].Subject)
+			continue
+		}
+		s := start
+		if s == "" {
+			var latest time.Time
+			for _, c := range chain {
+				if c.NotBefore.After(latest) {
+					latest = c.NotBefore
+				}
+			}
+			s = latest.UTC().Format(time.RFC3339)
+		}
+		ca, err := newCertificateAuthorityFromChain(chain, s, end, uri)
+		if err != nil {
+			return fmt.Errorf("chain %d: %w", i, err)
+		}
+		added = append(added, ca)
+	}
+	slices.SortStableFunc(added, func(a, b *ptr.CertificateAuthority) int {
+		return a.GetValidFor().GetStart().AsTime().Compare(b.GetValidFor().GetStart().AsTime())
+	})
+	*cas = append(*cas, added...)
+
+	return nil
+}
+
+// parseChains parses a Fulcio trust bundle, or a PEM chain.
+func parseChains(b []byte) ([][]*x509.Certificate, error) {
+	var chains [][]*x509.Certificate
+
+	if !bytes.HasPrefix(bytes.TrimSpace(b), []byte("{")) {
+		chain, err := parseChain(b, false)
+		if err != nil {
+			return nil, err
+		}
+		return [][]*x509.Certificate{chain}, nil
+	}
+
+	var bundle fulcioTrustBundle
+	if err := json.Unmarshal(b, &bundle); err != nil {
+		return nil, fmt.Errorf("invalid trust bundle: %w", err)
+	}
+	for i, c := range bundle.Chains {
+		chain, err := parseChain([]byte(strings.Join(c.Certificates, "\n")), false)
+		if err != nil {
+			return nil, fmt.Errorf("chain %d: %w", i, err)
+		}
+		if len(chain) == 0 {
+			return nil, fmt.Errorf("chain %d: no certificates", i)
+		}
+		chains = append(chains, chain)
+	}
+	if len(chains) == 0 {
+		return nil, errors.New("trust bundle has no chains")
+	}
+
+	return chains, nil
+}
+
+func sameChain(ca *ptr.CertificateAuthority, chain []*x509.Certificate) bool {
+	var certs = ca.GetCertChain().GetCertificates()
+
+	if len(certs) != len(chain) {
+		return false
+	}
+	for i, c := range chain {
+		if !bytes.Equal(certs[i].GetRawBytes(), c.Raw) {
+			return false
+		}
+	}
+
+	return true
+}
+
+// importKeys adds each PEM encoded key.
+func importKeys(w io.Writer, tr *ptr.TrustedRoot, tlogType, uri string, b []byte, start, end, padding string) error {
+	if start == "" {
+		return errors.New("keys have no validity start, set it with -start")
+	}
+
+	var tlogs = &tr.Tlogs
+	if tlogType == TypeCTLog {
+		tlogs = &tr.Ctlogs
+	}
+	var n int
+	for {
+		var block *pem.Block
+		if block, b = pem.Decode(b); block == nil {
+			break
+		}
+		n++
+		der, err := parsePubKey(pem.EncodeToMemory(block))
+		if err != nil {
+			return fmt.Errorf("key %d: %w", n-1, err)
+		}
+		if slices.ContainsFunc(*tlogs, func(tl *ptr.TransparencyLogInstance) bool {
+			return bytes.Equal(tl.GetPublicKey().GetRawBytes(), der)
+		}) {
+			fmt.Fprintf(w, "Skipping key %d, it is already in the trusted root\n", n-1)
+			continue
+		}
+		tl, err := newTLogFromKey(der, start, end, uri, padding)
+		if err != nil {
+			return fmt.Errorf("key %d: %w", n-1, err)
+		}
+		*tlogs = append(*tlogs, tl)
+	}
+	if n == 0 {
+		return errors.New("no PEM encoded keys found")
+	}
+
+	return nil
+}
@@ -0,0 +1,54 @@
+package app
+
+import (
+	"encoding/json"
+	"io"
+	"os"
+	"regexp"
+	"testing"
+
+	ptr "github.com/sigstore/protobuf-specs/gen/pb-go/trustroot/v1"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestImportResponse(t *testing.T) {
+	var bundle fulcioTrustBundle
+	var certRe = regexp.MustCompile(`(?s)-----BEGIN CERTIFICATE-----.*?-----END CERTIFICATE-----`)
+
+	for _, p := range []string{"tsa-chain.pem", "fulcio-chain.pem"} {
+		b, err := os.ReadFile("../../../test_data/" + p)
+		assert.Nil(t, err)
+		bundle.Chains = append(bundle.Chains, struct {
+			Certificates []string `json:"certificates"`
+		}{certRe.FindAllString(string(b), -1)})
+	}
+	b, err := json.Marshal(bundle)
+	assert.Nil(t, err)
+
+	var tr ptr.TrustedRoot
+	assert.Nil(t, importChains(io.Discard, &tr, TypeCA, "https://fulcio.test", b, "", ""))
+	assert.Len(t, tr.GetCertificateAuthorities(), 2)
+	first := tr.GetCertificateAuthorities()[0].GetValidFor().GetStart().AsTime()
+	second := tr.GetCertificateAuthorities()[1].GetValidFor().GetStart().AsTime()
+	assert.False(t, second.Before(first))
+
+	// Known chains are skipped
+	assert.Nil(t, importChains(io.Discard, &tr, TypeCA, "https://fulcio.test", b, "", ""))
+	assert.Len(t, tr.GetCertificateAuthorities(), 2)
+
+	b, err = os.ReadFile("../../../test_data/rekor.pkix.pem")
+	assert.Nil(t, err)
+	assert.ErrorContains(t, importKeys(io.Discard, &tr, TypeTLog, "https://rekor.test", b, "", "", RSAPKCS1v15),
+		"no validity start")
+	assert.Nil(t, importKeys(io.Discard, &tr, TypeTLog, "https://rekor.test", b, "2024-04-03T00:00:00Z", "", RSAPKCS1v15))
+	assert.Nil(t, importKeys(io.Discard, &tr, TypeTLog, "https://rekor.test", b, "2024-04-03T00:00:00Z", "", RSAPKCS1v15))
+	assert.Len(t, tr.GetTlogs(), 1)
+	assert.Equal(t, "https://rekor.test", tr.GetTlogs()[0].GetBaseUrl())
+
+	assert.ErrorContains(t, importKeys(io.Discard, &tr, TypeCTLog, "", []byte("{}"), "2024-04-03T00:00:00Z", "", RSAPKCS1v15),
+		"no PEM encoded keys")
+
+	// A malformed certificate is an error
+	_, err = parseChains([]byte("-----BEGIN CERTIFICATE-----\nMIIBAAAA\n-----END CERTIFICATE-----\n"))
+	assert.ErrorContains(t, err, "invalid certificate")
+}
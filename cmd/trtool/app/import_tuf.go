@@ -0,0 +1,393 @@
+package app
+
+import (
+	"context"
+	"crypto/x509"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/peterbourgon/ff/v3/ffcli"
+	ptr "github.com/sigstore/protobuf-specs/gen/pb-go/trustroot/v1"
+)
+
+// tufTargets is the part of a TUF targets.json used by the legacy
+// Sigstore layout, where each target has custom Sigstore metadata.
+type tufTargets struct {
+	Signed struct {
+		Targets map[string]struct {
+			Hashes map[string]string `json:"hashes"`
+			Custom struct {
+				Sigstore *tufSigstoreMeta `json:"sigstore"`
+			} `json:"custom"`
+		} `json:"targets"`
+	} `json:"signed"`
+}
+
+type tufSigstoreMeta struct {
+	Usage  string `json:"usage"`
+	Status string `json:"status"`
+	URI    string `json:"uri"`
+}
+
+// tufTarget is a legacy target to import. The chain is only loaded
+// for CAs and TSAs.
+type tufTarget struct {
+	name    string
+	path    string
+	typ     string
+	uri     string
+	expired bool
+	start   time.Time
+	chain   []*x509.Certificate
+}
+
+// tufUsages maps the legacy usage to the trusted root type.
+var tufUsages = map[string]string{
+	"fulcio": TypeCA,
+	"rekor":  TypeTLog,
+	"ctfe":   TypeCTLog,
+	"tsa":    TypeTSA,
+}
+
+func ImportTUFTargets() *ffcli.Command {
+	var (
+		flagset  = flag.NewFlagSet("trtool import-tuf-targets", flag.ExitOnError)
+		dir      = flagset.String("dir", "targets", "Directory with the target files")
+		targets  = flagset.String("targets", "targets.json", "TUF targets metadata")
+		start    = flagset.String("start", "", "Validity start for keys, which have no dates")
+		end      = flagset.String("end", "", "Validity end for expired targets. Defaults to now")
+		caURI    = flagset.String("ca-uri", "", "URI for CAs without one in the metadata")
+		tlogURI  = flagset.String("tlog-uri", "", "URI for transparency logs without one in the metadata")
+		ctlogURI = flagset.String("ctlog-uri", "", "URI for certificate transparency logs without one in the metadata")
+		tsaURI   = flagset.String("tsa-uri", "", "URI for TSAs without one in the metadata")
+		padding  = flagset.String("padding", "pkcs1v15", "For RSA keys, the padding scheme to use, pkcs1v15 or pss")
+		verbose  = flagset.Bool("v", false, "verbose mode")
+		out      = addOutputFlags(flagset)
+	)
+
+	return &ffcli.Command{
+		Name:       "import-tuf-targets",
+		ShortUsage: "trtool import-tuf-targets -dir ./targets -targets targets.json -start 2021-03-07T03:20:29Z",
+		ShortHelp:  "Build a trusted root from legacy TUF targets",
+		LongHelp: `Build a trusted root from the targets of a TUF repository that predates
+trusted_root.json, where each target has custom metadata like
+  "sigstore": {"usage": "Fulcio", "status": "Active", "uri": "..."}
+Usages Fulcio, Rekor, CTFE and TSA are imported, other targets are skipped.
+The layout has no validity periods: certificate chains start at the latest
+'not before' of the chain and keys at -start. Expired targets end at -end,
+but not after the earliest 'not after' of a chain. Active targets are open.
+Active keys of a log that also has expired keys start at -end, so the keys
+do not overlap, set -end to the time of the rotation.
+Intermediates shipped as targets of their own are joined with the target
+of the same usage that has their issuer.
+Target files are looked up by name, or with the hash prefix of consistent
+snapshots.`,
+		FlagSet: flagset,
+		Exec: func(ctx context.Context, args []string) error {
+			if *padding != RSAPKCS1v15 && *padding != RSAPSS {
+				return fmt.Errorf("invalid RSA padding: %w", flag.ErrHelp)
+			}
+			uris := map[string]string{
+				TypeCA:    *caURI,
+				TypeTLog:  *tlogURI,
+				TypeCTLog: *ctlogURI,
+				TypeTSA:   *tsaURI,
+			}
+
+			return ImportTUFTargetsCmd(os.Stderr, *dir, *targets, *start, *end, uris, *padding, *verbose, *out)
+		},
+	}
+}
+
+func ImportTUFTargetsCmd(w io.Writer, dir, targetsPath, start, end string, uris map[string]string,
+	padding string, verbose bool, out OutputOptions) error {
+	var endTs = time.Now().UTC().Truncate(time.Second)
+	if end != "" {
+		var err error
+		if endTs, err = time.Parse(time.RFC3339, end); err != nil {
+			return fmt.Errorf("invalid end %s: %w", end, err)
+		}
+	}
+
+	b, err := os.ReadFile(targetsPath)
+	if err != nil {
+		return fmt.Errorf("could not read targets %s: %w", targetsPath, err)
+	}
+	var meta tufTargets
+	if err = json.Unmarshal(b, &meta); err != nil {
+		return fmt.Errorf("invalid targets %s: %w", targetsPath, err)
+	}
+
+	found, err := tufLegacyTargets(w, &meta, dir, uris, verbose)
+	if err != nil {
+		return err
+	}
+	tr, err := importTUFTargets(found, start, endTs, padding, verbose)
+	if err != nil {
+		return err
+	}
+
+	return printProto(tr, out)
+}
+
+// tufLegacyTargets returns the targets with a known usage, sorted by
+// name. Targets with another usage are reported to w and skipped.
+func tufLegacyTargets(w io.Writer, meta *tufTargets, dir string, uris map[string]string, verbose bool) ([]*tufTarget, error) {
+	var found []*tufTarget
+	var names []string
+
+	for name := range meta.Signed.Targets {
+		names = append(names, name)
+	}
+	slices.Sort(names)
+
+	for _, name := range names {
+		t := meta.Signed.Targets[name]
+		sm := t.Custom.Sigstore
+		if sm == nil {
+			continue
+		}
+		if !filepath.IsLocal(filepath.FromSlash(name)) {
+			return nil, fmt.Errorf("%s: target name is not a local path", name)
+		}
+		typ, ok := tufUsages[strings.ToLower(sm.Usage)]
+		if !ok {
+			fmt.Fprintf(w, "Skipping %s with usage %q\n", name, sm.Usage)
+			continue
+		}
+		var expired bool
+		switch strings.ToLower(sm.Status) {
+		case "active":
+		case "expired":
+			expired = true
+		default:
+			return nil, fmt.Errorf("%s: unknown status %q, expected Active or Expired", name, sm.Status)
+		}
+
+		path := filepath.Join(dir, filepath.FromSlash(name))
+		if _, err := os.Stat(path); err != nil && t.Hashes["sha256"] != "" {
+			// Consistent snapshots prefix the file name with its hash
+			path = filepath.Join(filepath.Dir(path), t.Hashes["sha256"]+"."+filepath.Base(path))
+		}
+		uri := sm.URI
+		if uri == "" {
+			uri = uris[typ]
+		}
+		if verbose {
+			fmt.Fprintf(w, "Importing %s as %s %s\n", name, typ, uri)
+		}
+		found = append(found, &tufTarget{
+			name:    name,
+			path:    path,
+			typ:     typ,
+			uri:     uri,
+			expired: expired,
+		})
+	}
+
+	return found, nil
+}
+
+// importTUFTargets builds the trusted root. Entries of a type are
+// ordered by start, expired before active ones. Keys have no dates, so
+// they start at start, except active keys of a log with expired keys,
+// which start at end when the expired keys end.
+func importTUFTargets(targets []*tufTarget, start string, end time.Time, padding string, verbose bool) (*ptr.TrustedRoot, error) {
+	var keyStart time.Time
+	var err error
+
+	// Logs, by type and URI, with expired keys
+	var rotated = map[[2]string]bool{}
+	for _, t := range targets {
+		if t.expired {
+			rotated[[2]string{t.typ, t.uri}] = true
+		}
+	}
+
+	for _, t := range targets {
+		if t.typ != TypeCA && t.typ != TypeTSA {
+			continue
+		}
+		b, err := os.ReadFile(t.path)
+		if err != nil {
+			return nil, fmt.Errorf("%s: failed to load pem file: %w", t.name, err)
+		}
+		if t.chain, err = parseCertificates(b, verbose); err != nil {
+			return nil, fmt.Errorf("%s: %w", t.name, err)
+		}
+		if len(t.chain) == 0 {
+			return nil, fmt.Errorf("%s: no certificates", t.name)
+		}
+	}
+	if targets, err = joinIntermediates(targets); err != nil {
+		return nil, err
+	}
+
+	for _, t := range targets {
+		switch t.typ {
+		case TypeCA, TypeTSA:
+			for _, c := range t.chain {
+				if c.NotBefore.After(t.start) {
+					t.start = c.NotBefore.UTC()
+				}
+			}
+		default:
+			if start == "" {
+				return nil, fmt.Errorf("%s: keys have no validity start, set it with -start", t.name)
+			}
+			if keyStart.IsZero() {
+				if keyStart, err = time.Parse(time.RFC3339, start); err != nil {
+					return nil, fmt.Errorf("invalid start %s: %w", start, err)
+				}
+			}
+			t.start = keyStart
+			if !t.expired && rotated[[2]string{t.typ, t.uri}] {
+				t.start = end
+			}
+		}
+	}
+	slices.SortStableFunc(targets, func(a, b *tufTarget) int {
+		if c := a.start.Compare(b.start); c != 0 {
+			return c
+		}
+		switch {
+		case a.expired && !b.expired:
+			return -1
+		case !a.expired && b.expired:
+			return 1
+		}
+		return 0
+	})
+
+	v, err := lookupVersion(KindTrustedRoot, DefaultTrustedRootVersion)
+	if err != nil {
+		return nil, err
+	}
+	var tr = ptr.TrustedRoot{
+		MediaType: v.MediaType,
+	}
+	for _, t := range targets {
+		var e = ManifestEntry{
+			URI:   t.uri,
+			PEM:   t.path,
+			Start: t.start.Format(time.RFC3339),
+		}
+		if t.typ == TypeTLog || t.typ == TypeCTLog {
+			e.Padding = padding
+		}
+		if t.expired {
+			tEnd := end
+			for _, c := range t.chain {
+				if c.NotAfter.Before(tEnd) {
+					tEnd = c.NotAfter.UTC()
+				}
+			}
+			if !tEnd.After(t.start) {
+				return nil, fmt.Errorf("%s: expired at %s, before its start %s",
+					t.name, tEnd.Format(time.RFC3339), e.Start)
+			}
+			e.End = tEnd.Format(time.RFC3339)
+		}
+		if t.chain == nil {
+			if err = appendEntry(&tr, t.typ, e, "", verbose); err != nil {
+				return nil, fmt.Errorf("%s: %w", t.name, err)
+			}
+			continue
+		}
+		ca, err := newCertificateAuthorityFromChain(t.chain, e.Start, e.End, e.URI)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", t.name, err)
+		}
+		if t.typ == TypeCA {
+			tr.CertificateAuthorities = append(tr.CertificateAuthorities, ca)
+		} else {
+			tr.TimestampAuthorities = append(tr.TimestampAuthorities, ca)
+		}
+	}
+
+	return &tr, nil
+}
+
+// joinIntermediates orders the chains of the CA and TSA targets. The
+// layout ships intermediates as targets of their own, so a chain
+// without a root is joined with the target of the same type that has
+// its issuer. The joined target replaces the root target, a root with
+// several intermediates gives a chain per intermediate.
+func joinIntermediates(targets []*tufTarget) ([]*tufTarget, error) {
+	var issuers = map[*tufTarget]*tufTarget{}
+	var joined = map[*tufTarget]bool{}
+
+	for _, t := range targets {
+		if t.chain == nil || hasRoot(t.chain) {
+			continue
+		}
+		issuer := topIssuer(t.chain)
+		i := slices.IndexFunc(targets, func(r *tufTarget) bool {
+			return r.typ == t.typ && hasRoot(r.chain) &&
+				slices.ContainsFunc(r.chain, func(c *x509.Certificate) bool {
+					return c.Subject.CommonName == issuer
+				})
+		})
+		if i < 0 {
+			return nil, fmt.Errorf("%s: no %s target with the issuer %q of the intermediate", t.name, t.typ, issuer)
+		}
+		issuers[t] = targets[i]
+		joined[targets[i]] = true
+	}
+
+	var result []*tufTarget
+	for _, t := range targets {
+		if t.chain == nil {
+			result = append(result, t)
+			continue
+		}
+		if joined[t] {
+			continue
+		}
+		if r, ok := issuers[t]; ok {
+			t = &tufTarget{
+				name:    t.name + " and " + r.name,
+				path:    t.path,
+				typ:     t.typ,
+				uri:     orDefault(t.uri, r.uri),
+				expired: t.expired || r.expired,
+				chain:   slices.Concat(t.chain, r.chain),
+			}
+		}
+		chain, err := orderCertChain(t.chain)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", t.name, err)
+		}
+		t.chain = chain
+		result = append(result, t)
+	}
+
+	return result, nil
+}
+
+// hasRoot tells if a chain has a self issued certificate.
+func hasRoot(chain []*x509.Certificate) bool {
+	return slices.ContainsFunc(chain, func(c *x509.Certificate) bool {
+		return c.Issuer.CommonName == c.Subject.CommonName
+	})
+}
+
+// topIssuer returns the issuer of the chain that is not part of it.
+func topIssuer(chain []*x509.Certificate) string {
+	for _, c := range chain {
+		if !slices.ContainsFunc(chain, func(s *x509.Certificate) bool {
+			return s.Subject.CommonName == c.Issuer.CommonName
+		}) {
+			return c.Issuer.CommonName
+		}
+	}
+
+	return ""
+}
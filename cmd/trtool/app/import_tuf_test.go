@@ -0,0 +1,86 @@
+package app
+
+import (
+	"crypto/x509"
+	"encoding/json"
+	"io"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestImportTUFTargets(t *testing.T) {
+	var meta tufTargets
+	var end = time.Date(2024, 12, 1, 0, 0, 0, 0, time.UTC)
+
+	assert.Nil(t, json.Unmarshal([]byte(`{"signed": {"targets": {
+  "fulcio-chain.pem": {"custom": {"sigstore": {"usage": "Fulcio", "status": "Active", "uri": "https://fulcio.test"}}},
+  "rekor.pkix.pem": {"custom": {"sigstore": {"usage": "Rekor", "status": "Active"}}},
+  "rekor.pkcs1.pem": {"custom": {"sigstore": {"usage": "Rekor", "status": "Expired"}}},
+  "artifact.pub": {"custom": {"sigstore": {"usage": "Unknown", "status": "Active"}}},
+  "trusted_root.json": {}
+}}}`), &meta))
+
+	targets, err := tufLegacyTargets(io.Discard, &meta, "../../../test_data",
+		map[string]string{TypeTLog: "https://rekor.test"}, false)
+	assert.Nil(t, err)
+	assert.Len(t, targets, 3)
+
+	_, err = importTUFTargets(targets, "", end, RSAPKCS1v15, false)
+	assert.ErrorContains(t, err, "keys have no validity start")
+
+	tr, err := importTUFTargets(targets, "2024-04-03T00:00:00Z", end, RSAPKCS1v15, false)
+	assert.Nil(t, err)
+	assert.Equal(t, "https://fulcio.test", tr.GetCertificateAuthorities()[0].GetUri())
+	assert.Nil(t, tr.GetCertificateAuthorities()[0].GetValidFor().GetEnd())
+	// The expired key is listed first, the active key starts when it
+	// ends
+	assert.Len(t, tr.GetTlogs(), 2)
+	assert.Equal(t, "https://rekor.test", tr.GetTlogs()[0].GetBaseUrl())
+	assert.Equal(t, end, tr.GetTlogs()[0].GetPublicKey().GetValidFor().GetEnd().AsTime())
+	assert.Equal(t, end, tr.GetTlogs()[1].GetPublicKey().GetValidFor().GetStart().AsTime())
+	assert.Nil(t, tr.GetTlogs()[1].GetPublicKey().GetValidFor().GetEnd())
+	assert.True(t, VerifyTrustedRoot(io.Discard, tr, false))
+
+	// Target names must stay within the target directory
+	var outside tufTargets
+	assert.Nil(t, json.Unmarshal([]byte(`{"signed": {"targets": {
+  "../rekor.pub": {"custom": {"sigstore": {"usage": "Rekor", "status": "Active"}}}
+}}}`), &outside))
+	_, err = tufLegacyTargets(io.Discard, &outside, "../../../test_data", nil, false)
+	assert.ErrorContains(t, err, "not a local path")
+}
+
+func TestImportTUFTargetsSplitIntermediate(t *testing.T) {
+	var meta tufTargets
+	var dir = "../../../test_data"
+
+	b, err := os.ReadFile(filepath.Join(dir, "tuf-targets-split.json"))
+	assert.Nil(t, err)
+	assert.Nil(t, json.Unmarshal(b, &meta))
+	targets, err := tufLegacyTargets(io.Discard, &meta, dir, nil, false)
+	assert.Nil(t, err)
+
+	// The intermediate is joined with the root that issued it
+	tr, err := importTUFTargets(targets, "2024-04-03T00:00:00Z", time.Now(), RSAPKCS1v15, false)
+	assert.Nil(t, err)
+	assert.Len(t, tr.GetCertificateAuthorities(), 1)
+	ca := tr.GetCertificateAuthorities()[0]
+	assert.Equal(t, "https://fulcio.test", ca.GetUri())
+	assert.Len(t, ca.GetCertChain().GetCertificates(), 2)
+	leaf, err := x509.ParseCertificate(ca.GetCertChain().GetCertificates()[0].GetRawBytes())
+	assert.Nil(t, err)
+	assert.Equal(t, "Fulcio Intermediate - offline", leaf.Subject.CommonName)
+	assert.True(t, VerifyTrustedRoot(io.Discard, tr, false))
+
+	// An intermediate needs a target with its issuer
+	targets, err = tufLegacyTargets(io.Discard, &meta, dir, nil, false)
+	assert.Nil(t, err)
+	targets[0].path = filepath.Join(dir, "intermediate-f2.crt")
+	targets[1].path = filepath.Join(dir, "intermediate-f2.crt")
+	_, err = importTUFTargets(targets, "2024-04-03T00:00:00Z", time.Now(), RSAPKCS1v15, false)
+	assert.ErrorContains(t, err, `no ca target with the issuer "Fulcio Intermediate - offline" of the intermediate`)
+}
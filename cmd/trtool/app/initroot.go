@@ -3,11 +3,9 @@ package app
 import (
 	"context"
 	"flag"
-	"fmt"
 
 	"github.com/peterbourgon/ff/v3/ffcli"
 	ptr "github.com/sigstore/protobuf-specs/gen/pb-go/trustroot/v1"
-	"google.golang.org/protobuf/encoding/protojson"
 )
 
 func InitRoot() *ffcli.Command {
@@ -21,7 +19,9 @@ func InitRoot() *ffcli.Command {
 		tsaEnd   = flagset.String("tsa-end", "", "Validity end date for the TSA")
 		caURI    = flagset.String("ca-uri", "", "URI for the CA")
 		tsaURI   = flagset.String("tsa-uri", "", "URI for the TSA")
+		version  = flagset.String("version", DefaultTrustedRootVersion, "Trusted root version to create, 0.1 or 0.2")
 		verbose  = flagset.Bool("v", false, "verbose mode")
+		out      = addOutputFlags(flagset)
 	)
 
 	return &ffcli.Command{
@@ -38,20 +38,23 @@ func InitRoot() *ffcli.Command {
 				return flag.ErrHelp
 			}
 
-			return InitRootCmd(*ca, *caStart, *caEnd, *caURI,
+			return InitRootCmd(*version, *ca, *caStart, *caEnd, *caURI,
 				*tsa, *tsaStart, *tsaEnd, *tsaURI,
-				*verbose)
+				*verbose, *out)
 		},
 	}
 }
 
-func InitRootCmd(ca, caStart, caEnd, caURI,
-	tsa, tsaStart, tsaEnd, tsaURI string, verbose bool) error {
+func InitRootCmd(version, ca, caStart, caEnd, caURI,
+	tsa, tsaStart, tsaEnd, tsaURI string, verbose bool, out OutputOptions) error {
+	v, err := lookupVersion(KindTrustedRoot, version)
+	if err != nil {
+		return err
+	}
+
 	var tr = ptr.TrustedRoot{
-		MediaType: "application/vnd.dev.sigstore.trustedroot+json;version=0.1",
+		MediaType: v.MediaType,
 	}
-	var b []byte
-	var err error
 
 	if ca != "" {
 		protoCA, err := newCertificateAuthority(ca, caStart,
@@ -75,11 +78,5 @@ func InitRootCmd(ca, caStart, caEnd, caURI,
 		}
 	}
 
-	if b, err = protojson.Marshal(&tr); err != nil {
-		return err
-	}
-
-	fmt.Println(string(b))
-
-	return nil
+	return printProto(&tr, out)
 }
@@ -0,0 +1,305 @@
+package app
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+
+	ptr "github.com/sigstore/protobuf-specs/gen/pb-go/trustroot/v1"
+	"google.golang.org/protobuf/encoding/protojson"
+	"google.golang.org/protobuf/proto"
+	"gopkg.in/yaml.v3"
+)
+
+// InputOptions controls how trusted roots and signing configs are
+// parsed.
+type InputOptions struct {
+	// Format is one of json, yaml or pb (binary protobuf).
+	Format string
+	// Strict rejects documents with validation findings, see the
+	// validate command.
+	Strict bool
+}
+
+// DefaultInputOptions reads JSON.
+var DefaultInputOptions = InputOptions{
+	Format: FormatJSON,
+}
+
+func addInputFlags(fs *flag.FlagSet) *InputOptions {
+	var o InputOptions
+
+	fs.StringVar(&o.Format, "in-format", DefaultInputOptions.Format, "Input format, json, yaml or pb (binary protobuf)")
+	fs.BoolVar(&o.Strict, "strict", DefaultInputOptions.Strict, "Reject unknown fields and media types, duplicate keys and missing required fields")
+
+	return &o
+}
+
+// unmarshalProto parses b in the configured format into m.
+func unmarshalProto(b []byte, m proto.Message, opts InputOptions) error {
+	var err error
+
+	if opts.Strict {
+		findings, err := validate(b, m, opts.Format)
+		if err != nil {
+			return err
+		}
+		if len(findings) > 0 {
+			return &ValidationError{Findings: findings}
+		}
+		return nil
+	}
+
+	switch opts.Format {
+	case FormatProto:
+		return proto.Unmarshal(b, m)
+	case FormatYAML:
+		if b, err = yamlToJSON(b); err != nil {
+			return err
+		}
+	case "", FormatJSON:
+	default:
+		return fmt.Errorf("unsupported input format %s", opts.Format)
+	}
+
+	if sc, ok := m.(*ptr.SigningConfig); ok && isV01MediaType(b) {
+		return unmarshalSigningConfigV01(b, sc)
+	}
+
+	return protojson.Unmarshal(b, m)
+}
+
+func readTrustedRoot(p string, opts InputOptions) (*ptr.TrustedRoot, error) {
+	var tr ptr.TrustedRoot
+
+	b, err := os.ReadFile(p)
+	if err != nil {
+		return nil, fmt.Errorf("could not read trusted root %s: %w", p, err)
+	}
+	if err = unmarshalProto(b, &tr, opts); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal trusted root: %w", err)
+	}
+
+	return &tr, nil
+}
+
+func readSigningConfig(p string, opts InputOptions) (*ptr.SigningConfig, error) {
+	var sc ptr.SigningConfig
+
+	b, err := os.ReadFile(p)
+	if err != nil {
+		return nil, fmt.Errorf("could not read signing config %s: %w", p, err)
+	}
+	if err = unmarshalProto(b, &sc, opts); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal signing config: %w", err)
+	}
+
+	return &sc, nil
+}
+
+// unmarshalDocument parses a trusted root, signing config or client
+// trust config, based on the media type.
+func unmarshalDocument(b []byte, opts InputOptions) (proto.Message, error) {
+	var m = newDocument(documentKindOf(b, opts))
+
+	if err := unmarshalProto(b, m, opts); err != nil {
+		return nil, err
+	}
+
+	return m, nil
+}
+
+// unmarshalTrustedRoot parses a trusted root, or takes it from a client
+// trust config.
+func unmarshalTrustedRoot(b []byte, opts InputOptions) (*ptr.TrustedRoot, error) {
+	var tr ptr.TrustedRoot
+
+	if documentKindOf(b, opts) == KindClientTrustConfig {
+		var ctc ptr.ClientTrustConfig
+		if err := unmarshalProto(b, &ctc, opts); err != nil {
+			return nil, err
+		}
+		if ctc.GetTrustedRoot() == nil {
+			return nil, errors.New("client trust config has no trusted root")
+		}
+		return ctc.GetTrustedRoot(), nil
+	}
+	if err := unmarshalProto(b, &tr, opts); err != nil {
+		return nil, err
+	}
+
+	return &tr, nil
+}
+
+// unmarshalSigningConfig parses a signing config, or takes it from a
+// client trust config.
+func unmarshalSigningConfig(b []byte, opts InputOptions) (*ptr.SigningConfig, error) {
+	var sc ptr.SigningConfig
+
+	if documentKindOf(b, opts) == KindClientTrustConfig {
+		var ctc ptr.ClientTrustConfig
+		if err := unmarshalProto(b, &ctc, opts); err != nil {
+			return nil, err
+		}
+		if ctc.GetSigningConfig() == nil {
+			return nil, errors.New("client trust config has no signing config")
+		}
+		return ctc.GetSigningConfig(), nil
+	}
+	if err := unmarshalProto(b, &sc, opts); err != nil {
+		return nil, err
+	}
+
+	return &sc, nil
+}
+
+// newDocument returns an empty message of the kind, a trusted root if
+// the kind is not known.
+func newDocument(kind string) proto.Message {
+	switch kind {
+	case KindSigningConfig:
+		return &ptr.SigningConfig{}
+	case KindClientTrustConfig:
+		return &ptr.ClientTrustConfig{}
+	}
+
+	return &ptr.TrustedRoot{}
+}
+
+// documentKindOf looks at the media type to tell the kind of document,
+// defaulting to a trusted root.
+func documentKindOf(b []byte, opts InputOptions) string {
+	var err error
+
+	switch opts.Format {
+	case FormatProto:
+		// Field numbers overlap, so parse as each kind and look at
+		// the media type.
+		var ctc ptr.ClientTrustConfig
+		if proto.Unmarshal(b, &ctc) == nil && strings.Contains(ctc.MediaType, KindClientTrustConfig) {
+			return KindClientTrustConfig
+		}
+		var sc ptr.SigningConfig
+		if proto.Unmarshal(b, &sc) == nil && strings.Contains(sc.MediaType, KindSigningConfig) {
+			return KindSigningConfig
+		}
+		return KindTrustedRoot
+	case FormatYAML:
+		if b, err = yamlToJSON(b); err != nil {
+			return KindTrustedRoot
+		}
+	}
+
+	mt := peekMediaType(b)
+	for _, kind := range []string{KindClientTrustConfig, KindSigningConfig} {
+		if strings.Contains(mt, kind) {
+			return kind
+		}
+	}
+
+	return KindTrustedRoot
+}
+
+// isV01MediaType tells if a JSON signing config uses the v0.1 schema.
+func isV01MediaType(b []byte) bool {
+	v, ok := versionOf(KindSigningConfig, peekMediaType(b))
+	return ok && v.Version == "0.1"
+}
+
+func peekMediaType(b []byte) string {
+	var doc struct {
+		MediaType string `json:"mediaType"`
+	}
+
+	if err := json.Unmarshal(b, &doc); err != nil {
+		return ""
+	}
+
+	return doc.MediaType
+}
+
+// yamlToJSON converts a YAML document to JSON. Scalars are converted
+// based on their resolved tag, so quoted numbers stay strings and
+// timestamps are kept verbatim for protojson to parse.
+func yamlToJSON(b []byte) ([]byte, error) {
+	var n yaml.Node
+	var out bytes.Buffer
+
+	if err := yaml.Unmarshal(b, &n); err != nil {
+		return nil, err
+	}
+	if n.Kind == 0 {
+		return nil, errors.New("empty document")
+	}
+	if err := writeYAMLNode(&out, &n); err != nil {
+		return nil, err
+	}
+
+	return out.Bytes(), nil
+}
+
+func writeYAMLNode(out *bytes.Buffer, n *yaml.Node) error {
+	switch n.Kind {
+	case yaml.DocumentNode:
+		if len(n.Content) != 1 {
+			return fmt.Errorf("line %d: expected a single document", n.Line)
+		}
+		return writeYAMLNode(out, n.Content[0])
+	case yaml.AliasNode:
+		return writeYAMLNode(out, n.Alias)
+	case yaml.MappingNode:
+		out.WriteByte('{')
+		for i := 0; i+1 < len(n.Content); i += 2 {
+			if i > 0 {
+				out.WriteByte(',')
+			}
+			k, _ := json.Marshal(n.Content[i].Value)
+			out.Write(k)
+			out.WriteByte(':')
+			if err := writeYAMLNode(out, n.Content[i+1]); err != nil {
+				return err
+			}
+		}
+		out.WriteByte('}')
+	case yaml.SequenceNode:
+		out.WriteByte('[')
+		for i, c := range n.Content {
+			if i > 0 {
+				out.WriteByte(',')
+			}
+			if err := writeYAMLNode(out, c); err != nil {
+				return err
+			}
+		}
+		out.WriteByte(']')
+	case yaml.ScalarNode:
+		switch n.ShortTag() {
+		case "!!null":
+			out.WriteString("null")
+		case "!!bool":
+			v, err := strconv.ParseBool(n.Value)
+			if err != nil {
+				return fmt.Errorf("line %d: invalid bool %s", n.Line, n.Value)
+			}
+			out.WriteString(strconv.FormatBool(v))
+		case "!!int", "!!float":
+			// Let the JSON decoder validate the number
+			if !json.Valid([]byte(n.Value)) {
+				return fmt.Errorf("line %d: invalid number %s", n.Line, n.Value)
+			}
+			out.WriteString(n.Value)
+		default:
+			s, _ := json.Marshal(n.Value)
+			out.Write(s)
+		}
+	default:
+		return fmt.Errorf("line %d: unsupported YAML node", n.Line)
+	}
+
+	return nil
+}
@@ -0,0 +1,433 @@
+package app
+
+import (
+	"bytes"
+	"context"
+	"encoding/base64"
+	"encoding/pem"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"slices"
+	"time"
+
+	"github.com/peterbourgon/ff/v3/ffcli"
+	pc "github.com/sigstore/protobuf-specs/gen/pb-go/common/v1"
+	ptr "github.com/sigstore/protobuf-specs/gen/pb-go/trustroot/v1"
+	"gopkg.in/yaml.v3"
+)
+
+const (
+	K8sConfigMap = "configmap"
+	K8sSecret    = "secret"
+	K8sTrustRoot = "trustroot"
+)
+
+// k8sObject is the subset of a ConfigMap, Secret or policy-controller
+// TrustRoot that trtool reads and writes.
+type k8sObject struct {
+	APIVersion string            `yaml:"apiVersion"`
+	Kind       string            `yaml:"kind"`
+	Metadata   k8sMetadata       `yaml:"metadata"`
+	Type       string            `yaml:"type,omitempty"`
+	Data       map[string]string `yaml:"data,omitempty"`
+	Spec       *trustRootSpec    `yaml:"spec,omitempty"`
+}
+
+type k8sMetadata struct {
+	Name      string `yaml:"name"`
+	Namespace string `yaml:"namespace,omitempty"`
+}
+
+type trustRootSpec struct {
+	SigstoreKeys *sigstoreKeys `yaml:"sigstoreKeys,omitempty"`
+}
+
+// sigstoreKeys mirrors the policy-controller type, where certificate
+// chains and keys are base64 encoded PEM, and there are no validity
+// periods.
+type sigstoreKeys struct {
+	CertificateAuthorities []sigstoreKeysCA   `yaml:"certificateAuthorities,omitempty"`
+	TLogs                  []sigstoreKeysTLog `yaml:"tLogs,omitempty"`
+	CTLogs                 []sigstoreKeysTLog `yaml:"ctLogs,omitempty"`
+	TimestampAuthorities   []sigstoreKeysCA   `yaml:"timestampAuthorities,omitempty"`
+}
+
+type sigstoreKeysCA struct {
+	Subject   sigstoreKeysSubject `yaml:"subject"`
+	URI       string              `yaml:"uri"`
+	CertChain string              `yaml:"certChain"`
+}
+
+type sigstoreKeysSubject struct {
+	Organization string `yaml:"organization"`
+	CommonName   string `yaml:"commonName"`
+}
+
+type sigstoreKeysTLog struct {
+	BaseURL       string `yaml:"baseURL"`
+	HashAlgorithm string `yaml:"hashAlgorithm"`
+	PublicKey     string `yaml:"publicKey"`
+}
+
+// sigstoreKeysHashes maps the hash algorithms to their policy-controller
+// names.
+var sigstoreKeysHashes = map[pc.HashAlgorithm]string{
+	pc.HashAlgorithm_SHA2_256: "sha-256",
+	pc.HashAlgorithm_SHA2_384: "sha-384",
+	pc.HashAlgorithm_SHA2_512: "sha-512",
+}
+
+func ExportK8s() *ffcli.Command {
+	var (
+		flagset   = flag.NewFlagSet("trtool export-k8s", flag.ExitOnError)
+		file      = flagset.String("f", "trusted_root.json", "Trusted root to export")
+		kind      = flagset.String("kind", K8sConfigMap, "The resource, configmap, secret or trustroot")
+		name      = flagset.String("name", "trusted-root", "Name of the resource")
+		namespace = flagset.String("namespace", "", "Namespace of the ConfigMap or Secret")
+		key       = flagset.String("key", "trusted_root.json", "Data key of the ConfigMap or Secret")
+		at        = flagset.String("at", "", "For trustroot, export the entries valid at this time, RFC 3339. Defaults to now")
+		in        = addInputFlags(flagset)
+	)
+
+	return &ffcli.Command{
+		Name:       "export-k8s",
+		ShortUsage: "trtool export-k8s -f trusted_root.json -kind trustroot -name sigstore",
+		ShortHelp:  "Export a trusted root to a Kubernetes resource",
+		LongHelp: `Print a Kubernetes resource with a trusted root, or the trusted root of a
+client trust config:
+  configmap  a ConfigMap with trusted_root.json under -key
+  secret     a Secret with trusted_root.json under -key
+  trustroot  a policy-controller TrustRoot with the CAs, tlogs, ctlogs
+             and TSAs as sigstoreKeys
+The sigstoreKeys of a TrustRoot have no validity periods, only the
+entries valid now, or at -at, are exported.`,
+		FlagSet: flagset,
+		Exec: func(ctx context.Context, args []string) error {
+			if *kind != K8sConfigMap && *kind != K8sSecret && *kind != K8sTrustRoot {
+				return fmt.Errorf("invalid kind %q: %w", *kind, flag.ErrHelp)
+			}
+			if *kind == K8sTrustRoot && *namespace != "" {
+				return fmt.Errorf("TrustRoot is cluster scoped, it has no namespace: %w", flag.ErrHelp)
+			}
+
+			var now = time.Now()
+			if *at != "" {
+				var err error
+				if now, err = time.Parse(time.RFC3339, *at); err != nil {
+					return fmt.Errorf("invalid time %s: %w", *at, err)
+				}
+			}
+
+			return ExportK8sCmd(os.Stdout, *file, *kind, k8sMetadata{*name, *namespace}, *key, now, *in)
+		},
+	}
+}
+
+func ExportK8sCmd(w io.Writer, p, kind string, meta k8sMetadata, key string, now time.Time, in InputOptions) error {
+	b, err := os.ReadFile(p)
+	if err != nil {
+		return fmt.Errorf("could not read trusted root %s: %w", p, err)
+	}
+	tr, err := unmarshalTrustedRoot(b, in)
+	if err != nil {
+		return err
+	}
+
+	var obj = k8sObject{Metadata: meta}
+	switch kind {
+	case K8sConfigMap, K8sSecret:
+		b, err = marshalCanonical(tr, DefaultOutputOptions)
+		if err != nil {
+			return err
+		}
+		obj.APIVersion = "v1"
+		if kind == K8sConfigMap {
+			obj.Kind = "ConfigMap"
+			obj.Data = map[string]string{key: string(b)}
+		} else {
+			obj.Kind = "Secret"
+			obj.Type = "Opaque"
+			obj.Data = map[string]string{key: base64.StdEncoding.EncodeToString(b)}
+		}
+	case K8sTrustRoot:
+		keys, err := toSigstoreKeys(tr, now)
+		if err != nil {
+			return err
+		}
+		obj.APIVersion = "policy.sigstore.dev/v1alpha1"
+		obj.Kind = "TrustRoot"
+		obj.Spec = &trustRootSpec{SigstoreKeys: keys}
+	}
+
+	enc := yaml.NewEncoder(w)
+	enc.SetIndent(2)
+	if err = enc.Encode(&obj); err != nil {
+		return err
+	}
+
+	return enc.Close()
+}
+
+// toSigstoreKeys converts the entries valid at now to policy-controller
+// sigstoreKeys.
+func toSigstoreKeys(tr *ptr.TrustedRoot, now time.Time) (*sigstoreKeys, error) {
+	var keys sigstoreKeys
+
+	cas := func(entries []*ptr.CertificateAuthority) []sigstoreKeysCA {
+		var out []sigstoreKeysCA
+		for _, ca := range entries {
+			if !covers(ca.GetValidFor(), now) {
+				continue
+			}
+			out = append(out, sigstoreKeysCA{
+				Subject: sigstoreKeysSubject{
+					Organization: ca.GetSubject().GetOrganization(),
+					CommonName:   ca.GetSubject().GetCommonName(),
+				},
+				URI:       ca.GetUri(),
+				CertChain: base64.StdEncoding.EncodeToString(chainPEM(ca)),
+			})
+		}
+		return out
+	}
+	tlogs := func(field string, entries []*ptr.TransparencyLogInstance) ([]sigstoreKeysTLog, error) {
+		var out []sigstoreKeysTLog
+		for i, tl := range entries {
+			if !covers(tl.GetPublicKey().GetValidFor(), now) {
+				continue
+			}
+			hash, ok := sigstoreKeysHashes[tl.GetHashAlgorithm()]
+			if !ok {
+				return nil, fmt.Errorf("%s[%d]: unsupported hash algorithm %s", field, i, tl.GetHashAlgorithm())
+			}
+			key := pem.EncodeToMemory(&pem.Block{Type: "PUBLIC KEY", Bytes: tl.GetPublicKey().GetRawBytes()})
+			out = append(out, sigstoreKeysTLog{
+				BaseURL:       tl.GetBaseUrl(),
+				HashAlgorithm: hash,
+				PublicKey:     base64.StdEncoding.EncodeToString(key),
+			})
+		}
+		return out, nil
+	}
+
+	var err error
+	keys.CertificateAuthorities = cas(tr.GetCertificateAuthorities())
+	keys.TimestampAuthorities = cas(tr.GetTimestampAuthorities())
+	if keys.TLogs, err = tlogs("tlogs", tr.GetTlogs()); err != nil {
+		return nil, err
+	}
+	if keys.CTLogs, err = tlogs("ctlogs", tr.GetCtlogs()); err != nil {
+		return nil, err
+	}
+	if len(keys.CertificateAuthorities)+len(keys.TimestampAuthorities)+len(keys.TLogs)+len(keys.CTLogs) == 0 {
+		return nil, fmt.Errorf("no entries valid at %s", now.UTC().Format(time.RFC3339))
+	}
+
+	return &keys, nil
+}
+
+func ImportK8s() *ffcli.Command {
+	var (
+		flagset = flag.NewFlagSet("trtool import-k8s", flag.ExitOnError)
+		file    = flagset.String("f", "", "Kubernetes resource to import, can hold several documents")
+		name    = flagset.String("name", "", "Name of the resource, required if there are several")
+		key     = flagset.String("key", "trusted_root.json", "Data key of a ConfigMap or Secret")
+		start   = flagset.String("start", "", "For trustroot, the validity start of the logs, which have no dates")
+		padding = flagset.String("padding", "pkcs1v15", "For RSA keys, the padding scheme to use, pkcs1v15 or pss")
+		verbose = flagset.Bool("v", false, "verbose mode")
+		in      = addInputFlags(flagset)
+		out     = addOutputFlags(flagset)
+	)
+
+	return &ffcli.Command{
+		Name:       "import-k8s",
+		ShortUsage: "trtool import-k8s -f trustroot.yaml -start 2024-04-03T00:00:00Z",
+		ShortHelp:  "Import a trusted root from a Kubernetes resource",
+		LongHelp: `Read a trusted root from a ConfigMap or Secret, or convert the
+sigstoreKeys of a policy-controller TrustRoot, verify it and print it.
+The sigstoreKeys have no validity periods: certificate chains start at
+the latest 'not before' of the chain and logs at -start. All entries are
+open. Subjects are taken from the root certificates. TrustRoots with a
+remote or repository instead of sigstoreKeys can not be imported.`,
+		FlagSet: flagset,
+		Exec: func(ctx context.Context, args []string) error {
+			if *file == "" {
+				return fmt.Errorf("no resource provided: %w", flag.ErrHelp)
+			}
+			if *padding != RSAPKCS1v15 && *padding != RSAPSS {
+				return fmt.Errorf("invalid RSA padding: %w", flag.ErrHelp)
+			}
+
+			return ImportK8sCmd(os.Stderr, *file, *name, *key, *start, *padding, *verbose, *in, *out)
+		},
+	}
+}
+
+func ImportK8sCmd(w io.Writer, p, name, key, start, padding string, verbose bool, in InputOptions, out OutputOptions) error {
+	b, err := os.ReadFile(p)
+	if err != nil {
+		return fmt.Errorf("could not read resource %s: %w", p, err)
+	}
+	obj, err := readK8sObject(b, name)
+	if err != nil {
+		return err
+	}
+
+	var tr *ptr.TrustedRoot
+	switch obj.Kind {
+	case "ConfigMap", "Secret":
+		data, ok := obj.Data[key]
+		if !ok {
+			return fmt.Errorf("%s %s has no key %s", obj.Kind, obj.Metadata.Name, key)
+		}
+		b = []byte(data)
+		if obj.Kind == "Secret" {
+			if b, err = base64.StdEncoding.DecodeString(data); err != nil {
+				return fmt.Errorf("invalid Secret data %s: %w", key, err)
+			}
+		}
+		tr, err = unmarshalTrustedRoot(b, in)
+	default:
+		if obj.Spec == nil || obj.Spec.SigstoreKeys == nil {
+			return fmt.Errorf("TrustRoot %s has no sigstoreKeys", obj.Metadata.Name)
+		}
+		tr, err = fromSigstoreKeys(obj.Spec.SigstoreKeys, start, padding, verbose)
+	}
+	if err != nil {
+		return err
+	}
+
+	if !VerifyTrustedRoot(w, tr, verbose) {
+		return errors.New("verification failed")
+	}
+
+	return printProto(tr, out)
+}
+
+// readK8sObject returns the ConfigMap, Secret or TrustRoot in the YAML
+// stream, or the one with the name.
+func readK8sObject(b []byte, name string) (*k8sObject, error) {
+	var found []*k8sObject
+
+	dec := yaml.NewDecoder(bytes.NewReader(b))
+	for {
+		var obj k8sObject
+		if err := dec.Decode(&obj); errors.Is(err, io.EOF) {
+			break
+		} else if err != nil {
+			return nil, fmt.Errorf("invalid resource: %w", err)
+		}
+		if !slices.Contains([]string{"ConfigMap", "Secret", "TrustRoot"}, obj.Kind) {
+			continue
+		}
+		if name == "" || obj.Metadata.Name == name {
+			found = append(found, &obj)
+		}
+	}
+
+	switch {
+	case len(found) == 0 && name != "":
+		return nil, fmt.Errorf("no ConfigMap, Secret or TrustRoot named %s", name)
+	case len(found) == 0:
+		return nil, errors.New("no ConfigMap, Secret or TrustRoot found")
+	case len(found) > 1:
+		return nil, fmt.Errorf("found %d resources, select one with -name", len(found))
+	}
+
+	return found[0], nil
+}
+
+// fromSigstoreKeys converts policy-controller sigstoreKeys to a trusted
+// root, with the entries ordered by start.
+func fromSigstoreKeys(keys *sigstoreKeys, start, padding string, verbose bool) (*ptr.TrustedRoot, error) {
+	v, err := lookupVersion(KindTrustedRoot, DefaultTrustedRootVersion)
+	if err != nil {
+		return nil, err
+	}
+	var tr = ptr.TrustedRoot{
+		MediaType: v.MediaType,
+	}
+
+	cas := func(field string, entries []sigstoreKeysCA) ([]*ptr.CertificateAuthority, error) {
+		var out []*ptr.CertificateAuthority
+		for i, e := range entries {
+			b, err := base64.StdEncoding.DecodeString(e.CertChain)
+			if err != nil {
+				return nil, fmt.Errorf("%s[%d]: invalid certChain: %w", field, i, err)
+			}
+			chain, err := parseChain(b, verbose)
+			if err != nil {
+				return nil, fmt.Errorf("%s[%d]: %w", field, i, err)
+			}
+			if len(chain) == 0 {
+				return nil, fmt.Errorf("%s[%d]: no certificates", field, i)
+			}
+			var latest time.Time
+			for _, c := range chain {
+				if c.NotBefore.After(latest) {
+					latest = c.NotBefore
+				}
+			}
+			ca, err := newCertificateAuthorityFromChain(chain, latest.UTC().Format(time.RFC3339), "", e.URI)
+			if err != nil {
+				return nil, fmt.Errorf("%s[%d]: %w", field, i, err)
+			}
+			out = append(out, ca)
+		}
+		slices.SortStableFunc(out, func(a, b *ptr.CertificateAuthority) int {
+			return a.GetValidFor().GetStart().AsTime().Compare(b.GetValidFor().GetStart().AsTime())
+		})
+		return out, nil
+	}
+	tlogs := func(field string, entries []sigstoreKeysTLog) ([]*ptr.TransparencyLogInstance, error) {
+		var out []*ptr.TransparencyLogInstance
+		for i, e := range entries {
+			if start == "" {
+				return nil, fmt.Errorf("%s[%d]: keys have no validity start, set it with -start", field, i)
+			}
+			b, err := base64.StdEncoding.DecodeString(e.PublicKey)
+			if err != nil {
+				return nil, fmt.Errorf("%s[%d]: invalid publicKey: %w", field, i, err)
+			}
+			der, err := parsePubKey(b)
+			if err != nil {
+				return nil, fmt.Errorf("%s[%d]: %w", field, i, err)
+			}
+			tl, err := newTLogFromKey(der, start, "", e.BaseURL, padding)
+			if err != nil {
+				return nil, fmt.Errorf("%s[%d]: %w", field, i, err)
+			}
+			if e.HashAlgorithm != "" {
+				var ok bool
+				for h, n := range sigstoreKeysHashes {
+					if n == e.HashAlgorithm {
+						tl.HashAlgorithm, ok = h, true
+					}
+				}
+				if !ok {
+					return nil, fmt.Errorf("%s[%d]: unsupported hash algorithm %s", field, i, e.HashAlgorithm)
+				}
+			}
+			out = append(out, tl)
+		}
+		return out, nil
+	}
+
+	if tr.CertificateAuthorities, err = cas("certificateAuthorities", keys.CertificateAuthorities); err != nil {
+		return nil, err
+	}
+	if tr.TimestampAuthorities, err = cas("timestampAuthorities", keys.TimestampAuthorities); err != nil {
+		return nil, err
+	}
+	if tr.Tlogs, err = tlogs("tLogs", keys.TLogs); err != nil {
+		return nil, err
+	}
+	if tr.Ctlogs, err = tlogs("ctLogs", keys.CTLogs); err != nil {
+		return nil, err
+	}
+
+	return &tr, nil
+}
@@ -0,0 +1,124 @@
+package app
+
+import (
+	"bytes"
+	"encoding/base64"
+	"io"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"gopkg.in/yaml.v3"
+)
+
+func TestExportK8s(t *testing.T) {
+	var at = time.Date(2024, 12, 1, 0, 0, 0, 0, time.UTC)
+
+	_, tr := writeTestTrustedRoot(t, t.TempDir(), &RootManifest{
+		Tlogs: []ManifestEntry{{URI: "https://rekor.test", PEM: "rekor.pkix.pem", Start: "2024-04-03T00:00:00Z"}},
+	})
+
+	var out bytes.Buffer
+	var obj k8sObject
+	assert.Nil(t, ExportK8sCmd(&out, tr, K8sSecret, k8sMetadata{"root", "ns"}, "trusted_root.json", at, InputOptions{}))
+	assert.Nil(t, yaml.Unmarshal(out.Bytes(), &obj))
+	assert.Equal(t, "Secret", obj.Kind)
+	assert.Equal(t, "ns", obj.Metadata.Namespace)
+	b, err := base64.StdEncoding.DecodeString(obj.Data["trusted_root.json"])
+	assert.Nil(t, err)
+	assert.Contains(t, string(b), "https://rekor.test")
+
+	obj = k8sObject{}
+	out.Reset()
+	assert.Nil(t, ExportK8sCmd(&out, tr, K8sTrustRoot, k8sMetadata{Name: "root"}, "", at, InputOptions{}))
+	assert.Nil(t, yaml.Unmarshal(out.Bytes(), &obj))
+	assert.Equal(t, "TrustRoot", obj.Kind)
+	assert.Len(t, obj.Spec.SigstoreKeys.TLogs, 1)
+	assert.Equal(t, "sha-256", obj.Spec.SigstoreKeys.TLogs[0].HashAlgorithm)
+	key, err := base64.StdEncoding.DecodeString(obj.Spec.SigstoreKeys.TLogs[0].PublicKey)
+	assert.Nil(t, err)
+	pem, _ := os.ReadFile("../../../test_data/rekor.pkix.pem")
+	assert.Equal(t, string(bytes.TrimSpace(pem)), string(bytes.TrimSpace(key)))
+
+	assert.ErrorContains(t, ExportK8sCmd(&out, tr, K8sTrustRoot, k8sMetadata{Name: "root"}, "",
+		time.Date(2020, 1, 1, 0, 0, 0, 0, time.UTC), InputOptions{}), "no entries valid")
+}
+
+func TestImportK8s(t *testing.T) {
+	chain, err := os.ReadFile("../../../test_data/fulcio-chain.pem")
+	assert.Nil(t, err)
+	key, err := os.ReadFile("../../../test_data/rekor.pkix.pem")
+	assert.Nil(t, err)
+
+	stream := []byte(`apiVersion: v1
+kind: Namespace
+metadata:
+  name: cosign-system
+---
+apiVersion: policy.sigstore.dev/v1alpha1
+kind: TrustRoot
+metadata:
+  name: sigstore
+spec:
+  sigstoreKeys:
+    certificateAuthorities:
+      - subject:
+          organization: Test
+          commonName: Fulcio
+        uri: https://fulcio.test
+        certChain: ` + base64.StdEncoding.EncodeToString(chain) + `
+    tLogs:
+      - baseURL: https://rekor.test
+        hashAlgorithm: sha-256
+        publicKey: ` + base64.StdEncoding.EncodeToString(key) + `
+`)
+
+	obj, err := readK8sObject(stream, "")
+	assert.Nil(t, err)
+	assert.Equal(t, "sigstore", obj.Metadata.Name)
+	_, err = readK8sObject(stream, "other")
+	assert.ErrorContains(t, err, "no ConfigMap, Secret or TrustRoot named other")
+
+	_, err = fromSigstoreKeys(obj.Spec.SigstoreKeys, "", RSAPKCS1v15, false)
+	assert.ErrorContains(t, err, "tLogs[0]: keys have no validity start")
+
+	tr, err := fromSigstoreKeys(obj.Spec.SigstoreKeys, "2024-04-03T00:00:00Z", RSAPKCS1v15, false)
+	assert.Nil(t, err)
+	// The subject is the root's, not the one of the TrustRoot
+	assert.Equal(t, "Root", tr.GetCertificateAuthorities()[0].GetSubject().GetCommonName())
+	assert.Equal(t, "https://fulcio.test", tr.GetCertificateAuthorities()[0].GetUri())
+	assert.Equal(t, "https://rekor.test", tr.GetTlogs()[0].GetBaseUrl())
+	assert.True(t, VerifyTrustedRoot(io.Discard, tr, false))
+
+	// A malformed certificate is an error
+	obj.Spec.SigstoreKeys.CertificateAuthorities[0].CertChain = base64.StdEncoding.EncodeToString(
+		[]byte("-----BEGIN CERTIFICATE-----\nMIIBAAAA\n-----END CERTIFICATE-----\n"))
+	_, err = fromSigstoreKeys(obj.Spec.SigstoreKeys, "2024-04-03T00:00:00Z", RSAPKCS1v15, false)
+	assert.ErrorContains(t, err, "certificateAuthorities[0]: invalid certificate")
+
+	// A malformed or empty chain in a ConfigMap fails the verification
+	var dir = t.TempDir()
+	for chain, finding := range map[string]string{
+		`{"certificates": [{"rawBytes": "AAAA"}]}`: "Invalid certificate at pos 0 of https://fulcio.test",
+		`{}`: "Empty certificate chain for https://fulcio.test",
+	} {
+		cm := filepath.Join(dir, "cm.yaml")
+		assert.Nil(t, os.WriteFile(cm, []byte(`apiVersion: v1
+kind: ConfigMap
+metadata:
+  name: trusted-root
+data:
+  trusted_root.json: |
+    {"mediaType": "application/vnd.dev.sigstore.trustedroot+json;version=0.1",
+     "certificateAuthorities": [{"subject": {"organization": "Test", "commonName": "Root"},
+       "uri": "https://fulcio.test", "certChain": `+chain+`,
+       "validFor": {"start": "2024-04-03T00:00:00Z"}}]}
+`), 0644))
+		var w bytes.Buffer
+		err = ImportK8sCmd(&w, cm, "", "trusted_root.json", "", RSAPKCS1v15, false, DefaultInputOptions, OutputOptions{})
+		assert.ErrorContains(t, err, "verification failed")
+		assert.Contains(t, w.String(), finding)
+	}
+}
@@ -0,0 +1,130 @@
+package app
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+
+	"github.com/peterbourgon/ff/v3/ffcli"
+	ptr "github.com/sigstore/protobuf-specs/gen/pb-go/trustroot/v1"
+	"google.golang.org/protobuf/proto"
+)
+
+func Migrate() *ffcli.Command {
+	var (
+		flagset  = flag.NewFlagSet("trtool migrate", flag.ExitOnError)
+		file     = flagset.String("f", "", "Trusted root or signing config to migrate")
+		to       = flagset.String("to", "", "Version to migrate to, e.g. 0.2")
+		defaults = addServiceFlags(flagset)
+		in       = addInputFlags(flagset)
+		out      = addOutputFlags(flagset)
+	)
+
+	return &ffcli.Command{
+		Name:       "migrate",
+		ShortUsage: "trtool migrate -f signing_config.json -to 0.2",
+		ShortHelp:  "Migrate a trusted root or signing config to another version",
+		LongHelp: `Migrate a trusted root or signing config to another schema version
+and print the result.
+Trusted roots: 0.2 adds operators, which must be empty to migrate to 0.1.
+Signing configs: 0.2 replaces the URLs with services. When migrating to 0.2
+the services get API version 1, the validity start from -start and the
+operator from -operator, and all transparency logs and timestamp
+authorities are used. Migrating to 0.1
+fails if more than one CA or OIDC provider is configured, or if validity
+periods, API versions, operators or selectors would be lost.`,
+		FlagSet: flagset,
+		Exec: func(ctx context.Context, args []string) error {
+			if *file == "" || *to == "" {
+				return flag.ErrHelp
+			}
+
+			return MigrateCmd(*file, *to, *defaults, *in, *out)
+		},
+	}
+}
+
+func MigrateCmd(p, to string, defaults ServiceDefaults, in InputOptions, out OutputOptions) error {
+	m, err := readDocument(p, in)
+	if err != nil {
+		return err
+	}
+
+	if err = migrate(m, to, defaults); err != nil {
+		return err
+	}
+
+	return printProto(m, out)
+}
+
+// migrate converts the document in place to the given version.
+func migrate(m proto.Message, to string, defaults ServiceDefaults) error {
+	v, err := lookupVersion(documentKind(m), to)
+	if err != nil {
+		return err
+	}
+
+	switch m := m.(type) {
+	case *ptr.TrustedRoot:
+		if v.Version == "0.1" {
+			if err = checkV01Fields(m); err != nil {
+				return fmt.Errorf("trusted root can not be migrated to v0.1 without losing data:\n%w", err)
+			}
+		}
+		m.MediaType = v.MediaType
+	case *ptr.SigningConfig:
+		if _, ok := versionOf(KindSigningConfig, m.GetMediaType()); !ok {
+			return fmt.Errorf("unknown signing config media type %s", m.GetMediaType())
+		}
+		wasV01 := isSigningConfigV01(m)
+		m.MediaType = v.MediaType
+		switch {
+		case isSigningConfigV01(m):
+			// Fail now rather than when printing
+			if _, err = marshalSigningConfigV01(m); err != nil {
+				return err
+			}
+		case wasV01:
+			return defaults.applyAll(m)
+		}
+	case *ptr.ClientTrustConfig:
+		m.MediaType = v.MediaType
+	}
+
+	return nil
+}
+
+// checkV01Fields fails if the trusted root uses operators or
+// checkpoint key ids, which were added in v0.2.
+func checkV01Fields(tr *ptr.TrustedRoot) error {
+	var errs []error
+
+	for i, tl := range tr.GetTlogs() {
+		if tl.GetOperator() != "" {
+			errs = append(errs, fmt.Errorf("tlogs[%d].operator: not supported by v0.1", i))
+		}
+		if tl.GetCheckpointKeyId() != nil {
+			errs = append(errs, fmt.Errorf("tlogs[%d].checkpointKeyId: not supported by v0.1", i))
+		}
+	}
+	for i, ca := range tr.GetCertificateAuthorities() {
+		if ca.GetOperator() != "" {
+			errs = append(errs, fmt.Errorf("certificateAuthorities[%d].operator: not supported by v0.1", i))
+		}
+	}
+	for i, tl := range tr.GetCtlogs() {
+		if tl.GetOperator() != "" {
+			errs = append(errs, fmt.Errorf("ctlogs[%d].operator: not supported by v0.1", i))
+		}
+		if tl.GetCheckpointKeyId() != nil {
+			errs = append(errs, fmt.Errorf("ctlogs[%d].checkpointKeyId: not supported by v0.1", i))
+		}
+	}
+	for i, ca := range tr.GetTimestampAuthorities() {
+		if ca.GetOperator() != "" {
+			errs = append(errs, fmt.Errorf("timestampAuthorities[%d].operator: not supported by v0.1", i))
+		}
+	}
+	return errors.Join(errs...)
+}
@@ -0,0 +1,56 @@
+package app
+
+import (
+	"testing"
+
+	ptr "github.com/sigstore/protobuf-specs/gen/pb-go/trustroot/v1"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestMigrateSigningConfig(t *testing.T) {
+	var sc ptr.SigningConfig
+	var doc = []byte(`{
+  "mediaType": "application/vnd.dev.sigstore.signingconfig.v0.1+json",
+  "caUrl": "https://ca.test",
+  "tlogUrls": ["https://rekor.test"]
+}`)
+
+	assert.Nil(t, unmarshalProto(doc, &sc, DefaultInputOptions))
+	assert.Nil(t, migrate(&sc, "0.2", ServiceDefaults{Start: "2024-04-03T00:00:00Z", Operator: "test.com"}))
+
+	b, err := marshalCanonical(&sc, OutputOptions{Format: FormatJSON})
+	assert.Nil(t, err)
+	assert.Equal(t, `{"mediaType":"application/vnd.dev.sigstore.signingconfig.v0.2+json","caUrls":[{"url":"https://ca.test","majorApiVersion":1,"validFor":{"start":"2024-04-03T00:00:00Z"},"operator":"test.com"}],"rekorTlogUrls":[{"url":"https://rekor.test","majorApiVersion":1,"validFor":{"start":"2024-04-03T00:00:00Z"},"operator":"test.com"}],"rekorTlogConfig":{"selector":"ALL"}}`, string(b))
+
+	// Validity periods can not be expressed in v0.1
+	err = migrate(&sc, "0.1", ServiceDefaults{})
+	assert.ErrorContains(t, err, "caUrls[0].validFor: not supported by v0.1")
+
+	for _, s := range append(sc.CaUrls, sc.RekorTlogUrls...) {
+		s.ValidFor = nil
+		s.Operator = ""
+	}
+	assert.Nil(t, migrate(&sc, "0.1", ServiceDefaults{}))
+	b, err = marshalCanonical(&sc, OutputOptions{Format: FormatJSON})
+	assert.Nil(t, err)
+	assert.Equal(t, `{"mediaType":"application/vnd.dev.sigstore.signingconfig.v0.1+json","caUrl":"https://ca.test","tlogUrls":["https://rekor.test"]}`, string(b))
+
+	assert.ErrorContains(t, migrate(&sc, "0.3", ServiceDefaults{}), "unknown signingconfig version 0.3")
+}
+
+func TestMigrateTrustedRoot(t *testing.T) {
+	var tr = ptr.TrustedRoot{
+		MediaType: "application/vnd.dev.sigstore.trustedroot+json;version=0.1",
+	}
+
+	assert.Nil(t, migrate(&tr, "0.2", ServiceDefaults{}))
+	assert.Equal(t, "application/vnd.dev.sigstore.trustedroot.v0.2+json", tr.MediaType)
+
+	tr.Tlogs = []*ptr.TransparencyLogInstance{{Operator: "example.com"}}
+	assert.ErrorContains(t, migrate(&tr, "0.1", ServiceDefaults{}), "tlogs[0].operator")
+
+	// v0.1 is written with the legacy media type
+	tr.Tlogs = nil
+	assert.Nil(t, migrate(&tr, "0.1", ServiceDefaults{}))
+	assert.Equal(t, "application/vnd.dev.sigstore.trustedroot+json;version=0.1", tr.MediaType)
+}
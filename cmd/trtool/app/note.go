@@ -0,0 +1,194 @@
+package app
+
+import (
+	"bytes"
+	"context"
+	"crypto/ed25519"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/peterbourgon/ff/v3/ffcli"
+	pc "github.com/sigstore/protobuf-specs/gen/pb-go/common/v1"
+	ptr "github.com/sigstore/protobuf-specs/gen/pb-go/trustroot/v1"
+)
+
+// noteAlgEd25519 is the signed note signature type of Ed25519 keys.
+const noteAlgEd25519 = 0x01
+
+// noteKeyIDSize is the size of the key id of a signed note verifier,
+// the prefix of the key hash.
+const noteKeyIDSize = 4
+
+// noteKeyHash computes SHA-256(name || '\n' || type || key), the log
+// id of a tile based log. The first four bytes are the key id of the
+// signed note verifier, and the checkpoint key id.
+func noteKeyHash(name string, alg byte, key []byte) []byte {
+	h := sha256.New()
+	h.Write([]byte(name))
+	h.Write([]byte{'\n', alg})
+	h.Write(key)
+
+	return h.Sum(nil)
+}
+
+// parseNoteVerifier parses a signed note verifier,
+// name+hexkeyid+base64(type || key), and returns the name, the full
+// key hash and the key as PKIX DER.
+func parseNoteVerifier(v string) (string, []byte, []byte, error) {
+	name, rest, ok1 := strings.Cut(strings.TrimSpace(v), "+")
+	hash, key64, ok2 := strings.Cut(rest, "+")
+	if !ok1 || !ok2 || name == "" {
+		return "", nil, nil, errors.New("malformed note verifier, expected name+hash+key")
+	}
+	keyID, err := hex.DecodeString(hash)
+	if err != nil || len(keyID) != noteKeyIDSize {
+		return "", nil, nil, fmt.Errorf("invalid note verifier key hash %q", hash)
+	}
+	key, err := base64.StdEncoding.DecodeString(key64)
+	if err != nil || len(key) == 0 {
+		return "", nil, nil, errors.New("invalid note verifier key encoding")
+	}
+	if key[0] != noteAlgEd25519 {
+		return "", nil, nil, fmt.Errorf("unsupported note verifier key type 0x%02x, only Ed25519 is supported", key[0])
+	}
+	if len(key) != 1+ed25519.PublicKeySize {
+		return "", nil, nil, errors.New("invalid Ed25519 key length")
+	}
+	keyHash := noteKeyHash(name, key[0], key[1:])
+	if !bytes.Equal(keyID, keyHash[:noteKeyIDSize]) {
+		return "", nil, nil, fmt.Errorf("key hash %s does not match the name and key", hash)
+	}
+	der, err := x509.MarshalPKIXPublicKey(ed25519.PublicKey(key[1:]))
+	if err != nil {
+		return "", nil, nil, err
+	}
+
+	return name, keyHash, der, nil
+}
+
+// newTLogFromNoteVerifier creates a tlog from a signed note verifier.
+// The log id is the key hash and the checkpoint key id its first four
+// bytes. The base URL defaults to the origin, which it must match.
+func newTLogFromNoteVerifier(v, start, end, uri string) (*ptr.TransparencyLogInstance, error) {
+	origin, keyHash, der, err := parseNoteVerifier(v)
+	if err != nil {
+		return nil, err
+	}
+	if uri == "" {
+		uri = "https://" + origin
+	} else if noteOrigin(uri) != origin {
+		return nil, fmt.Errorf("uri %s does not match the checkpoint origin %s", uri, origin)
+	}
+	tl, err := newTLogFromKey(der, start, end, uri, RSAPKCS1v15)
+	if err != nil {
+		return nil, err
+	}
+	tl.LogId = &pc.LogId{KeyId: keyHash}
+	tl.CheckpointKeyId = &pc.LogId{KeyId: keyHash[:noteKeyIDSize]}
+
+	return tl, nil
+}
+
+// noteOrigin returns the checkpoint origin of a base URL, which is
+// the URL without the scheme.
+func noteOrigin(uri string) string {
+	if _, rest, ok := strings.Cut(uri, "://"); ok {
+		uri = rest
+	}
+
+	return strings.TrimSuffix(uri, "/")
+}
+
+// noteVerifier returns the signed note verifier of a tlog with an
+// Ed25519 key.
+func noteVerifier(tl *ptr.TransparencyLogInstance) (string, error) {
+	pub, err := x509.ParsePKIXPublicKey(tl.GetPublicKey().GetRawBytes())
+	if err != nil {
+		return "", fmt.Errorf("invalid public key: %w", err)
+	}
+	key, ok := pub.(ed25519.PublicKey)
+	if !ok {
+		return "", fmt.Errorf("%T keys have no note verifier, only Ed25519 is supported", pub)
+	}
+	name := noteOrigin(tl.GetBaseUrl())
+	if name == "" || strings.ContainsAny(name, "+ \t\n") {
+		return "", fmt.Errorf("invalid checkpoint origin %q", name)
+	}
+	keyID := noteKeyHash(name, noteAlgEd25519, key)[:noteKeyIDSize]
+	if id := tl.GetCheckpointKeyId().GetKeyId(); id != nil && !bytes.Equal(id, keyID) {
+		return "", fmt.Errorf("checkpoint key id %x does not match the verifier key id %x", id, keyID)
+	}
+
+	return fmt.Sprintf("%s+%x+%s", name, keyID,
+		base64.StdEncoding.EncodeToString(append([]byte{noteAlgEd25519}, key...))), nil
+}
+
+func ExportNoteVerifiers() *ffcli.Command {
+	var (
+		flagset  = flag.NewFlagSet("trtool export-note-verifiers", flag.ExitOnError)
+		file     = flagset.String("f", "trusted_root.json", "Trusted root to export from")
+		activeAt = flagset.String("active-at", "", "Only export entries valid at this time, RFC 3339. Defaults to all entries")
+		in       = addInputFlags(flagset)
+	)
+
+	return &ffcli.Command{
+		Name:       "export-note-verifiers",
+		ShortUsage: "trtool export-note-verifiers -f trusted_root.json",
+		ShortHelp:  "Export the tlog keys as signed note verifiers",
+		LongHelp: `Print the Ed25519 keys of the tlogs of a trusted root, or the trusted
+root of a client trust config, as signed note verifiers, one per line.
+The name is the base URL without the scheme, the checkpoint origin.
+Tlogs with other keys are skipped.`,
+		FlagSet: flagset,
+		Exec: func(ctx context.Context, args []string) error {
+			var at time.Time
+			if *activeAt != "" {
+				var err error
+				if at, err = time.Parse(time.RFC3339, *activeAt); err != nil {
+					return fmt.Errorf("invalid time %s: %w", *activeAt, err)
+				}
+			}
+
+			return ExportNoteVerifiersCmd(os.Stdout, os.Stderr, *file, at, *in)
+		},
+	}
+}
+
+func ExportNoteVerifiersCmd(w, errw io.Writer, p string, at time.Time, in InputOptions) error {
+	b, err := os.ReadFile(p)
+	if err != nil {
+		return fmt.Errorf("could not read trusted root %s: %w", p, err)
+	}
+	tr, err := unmarshalTrustedRoot(b, in)
+	if err != nil {
+		return err
+	}
+
+	var n int
+	for i, tl := range tr.GetTlogs() {
+		if !at.IsZero() && !covers(tl.GetPublicKey().GetValidFor(), at) {
+			continue
+		}
+		v, err := noteVerifier(tl)
+		if err != nil {
+			fmt.Fprintf(errw, "Skipping tlogs[%d] %s: %v\n", i, tl.GetBaseUrl(), err)
+			continue
+		}
+		fmt.Fprintln(w, v)
+		n++
+	}
+	if n == 0 {
+		return errors.New("no tlogs with note verifiers")
+	}
+
+	return nil
+}
@@ -0,0 +1,35 @@
+package app
+
+import (
+	"encoding/hex"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestNoteVerifier(t *testing.T) {
+	const v = "log2025.rekor.test+863fc2b6+AQABAgMEBQYHCAkKCwwNDg8QERITFBUWFxgZGhscHR4f"
+
+	name, keyHash, _, err := parseNoteVerifier(v)
+	assert.Nil(t, err)
+	assert.Equal(t, "log2025.rekor.test", name)
+	assert.Equal(t, "863fc2b67d257accd4138a7b6beac6b0273e79902667bc89d374b90170720590", hex.EncodeToString(keyHash))
+
+	_, _, _, err = parseNoteVerifier("other.test+863fc2b6+AQABAgMEBQYHCAkKCwwNDg8QERITFBUWFxgZGhscHR4f")
+	assert.ErrorContains(t, err, "does not match")
+	_, _, _, err = parseNoteVerifier("log2025.rekor.test+863fc2b6+AgABAgMEBQYHCAkKCwwNDg8QERITFBUWFxgZGhscHR4f")
+	assert.ErrorContains(t, err, "unsupported note verifier key type 0x02")
+
+	tl, err := newTLogFromNoteVerifier(v, "2025-01-01T00:00:00Z", "", "")
+	assert.Nil(t, err)
+	assert.Equal(t, "https://log2025.rekor.test", tl.GetBaseUrl())
+	assert.Equal(t, keyHash, tl.GetLogId().GetKeyId())
+	assert.Equal(t, "863fc2b6", hex.EncodeToString(tl.GetCheckpointKeyId().GetKeyId()))
+
+	_, err = newTLogFromNoteVerifier(v, "2025-01-01T00:00:00Z", "", "https://rekor.test")
+	assert.ErrorContains(t, err, "does not match the checkpoint origin")
+
+	exported, err := noteVerifier(tl)
+	assert.Nil(t, err)
+	assert.Equal(t, v, exported)
+}
@@ -0,0 +1,159 @@
+package app
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+	"strings"
+
+	ptr "github.com/sigstore/protobuf-specs/gen/pb-go/trustroot/v1"
+	"google.golang.org/protobuf/encoding/protojson"
+	"google.golang.org/protobuf/proto"
+	"gopkg.in/yaml.v3"
+)
+
+const (
+	FormatJSON  = "json"
+	FormatYAML  = "yaml"
+	FormatProto = "pb"
+)
+
+// OutputOptions controls how trusted roots and signing configs are
+// serialized.
+type OutputOptions struct {
+	// Format is one of json, yaml or pb (binary protobuf).
+	Format string
+	// Indent is the number of spaces to indent with, zero means
+	// everything is printed on a single line. Only used for JSON and
+	// YAML, where YAML always is indented by at least two spaces.
+	Indent int
+	// Newline terminates the output with a newline. Not used for
+	// binary output.
+	Newline bool
+}
+
+// DefaultOutputOptions is the canonical form, as produced by the fmt
+// command.
+var DefaultOutputOptions = OutputOptions{
+	Format:  FormatJSON,
+	Indent:  2,
+	Newline: true,
+}
+
+func addOutputFlags(fs *flag.FlagSet) *OutputOptions {
+	var o OutputOptions
+
+	fs.StringVar(&o.Format, "out-format", DefaultOutputOptions.Format, "Output format, json, yaml or pb (binary protobuf)")
+	fs.IntVar(&o.Indent, "indent", DefaultOutputOptions.Indent, "Number of spaces to indent the output with, 0 prints a single line")
+	fs.BoolVar(&o.Newline, "newline", DefaultOutputOptions.Newline, "Terminate the output with a newline")
+
+	return &o
+}
+
+// marshalCanonical serializes the message in a stable form.
+// protojson emits fields in declaration order but randomly varies the
+// whitespace, so the output is compacted and re-indented.
+func marshalCanonical(m proto.Message, opts OutputOptions) ([]byte, error) {
+	var compact bytes.Buffer
+	var b []byte
+	var err error
+
+	sc, legacy := m.(*ptr.SigningConfig)
+	legacy = legacy && isSigningConfigV01(sc)
+
+	if opts.Format == FormatProto {
+		if legacy {
+			return nil, errors.New("signing config v0.1 can only be written as JSON or YAML")
+		}
+		return proto.MarshalOptions{Deterministic: true}.Marshal(m)
+	}
+	if opts.Format != "" && opts.Format != FormatJSON && opts.Format != FormatYAML {
+		return nil, fmt.Errorf("unsupported output format %s", opts.Format)
+	}
+
+	if legacy {
+		b, err = marshalSigningConfigV01(sc)
+	} else {
+		b, err = protojson.Marshal(m)
+	}
+	if err != nil {
+		return nil, err
+	}
+	if err = json.Compact(&compact, b); err != nil {
+		return nil, err
+	}
+
+	b = compact.Bytes()
+	if opts.Format == FormatYAML {
+		if b, err = jsonToYAML(b, opts.Indent); err != nil {
+			return nil, err
+		}
+		// The YAML encoder always terminates the document
+		if !opts.Newline {
+			b = bytes.TrimSuffix(b, []byte("\n"))
+		}
+		return b, nil
+	}
+
+	if opts.Indent > 0 {
+		var indented bytes.Buffer
+		err = json.Indent(&indented, b, "", strings.Repeat(" ", opts.Indent))
+		if err != nil {
+			return nil, err
+		}
+		b = indented.Bytes()
+	}
+	if opts.Newline {
+		b = append(b, '\n')
+	}
+
+	return b, nil
+}
+
+// jsonToYAML converts a JSON document to YAML, keeping the order of
+// the fields. JSON is valid YAML, so the document is parsed as YAML
+// and the flow style is dropped.
+func jsonToYAML(b []byte, indent int) ([]byte, error) {
+	var n yaml.Node
+	var out bytes.Buffer
+
+	if err := yaml.Unmarshal(b, &n); err != nil {
+		return nil, err
+	}
+	resetStyle(&n)
+
+	if indent < 2 {
+		indent = 2
+	}
+	enc := yaml.NewEncoder(&out)
+	enc.SetIndent(indent)
+	if err := enc.Encode(&n); err != nil {
+		return nil, err
+	}
+	if err := enc.Close(); err != nil {
+		return nil, err
+	}
+
+	return out.Bytes(), nil
+}
+
+func resetStyle(n *yaml.Node) {
+	n.Style = 0
+	for _, c := range n.Content {
+		resetStyle(c)
+	}
+}
+
+// printProto writes the message in canonical form to stdout.
+func printProto(m proto.Message, opts OutputOptions) error {
+	b, err := marshalCanonical(m, opts)
+	if err != nil {
+		return err
+	}
+
+	_, err = os.Stdout.Write(b)
+	return err
+}
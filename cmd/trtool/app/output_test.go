@@ -0,0 +1,54 @@
+package app
+
+import (
+	"testing"
+
+	ptr "github.com/sigstore/protobuf-specs/gen/pb-go/trustroot/v1"
+	"github.com/stretchr/testify/assert"
+	"google.golang.org/protobuf/proto"
+)
+
+func TestRoundTrip(t *testing.T) {
+	ca, err := newCertificateAuthority("../../../test_data/fulcio-chain.pem",
+		"2024-04-03T00:00:00Z", "2025-01-01T00:00:00Z", "https://fulcio.test", false)
+	assert.Nil(t, err)
+	tlog, err := newTLog("../../../test_data/rekor.pkcs1.pem",
+		"2024-04-03T00:00:00Z", "", "https://rekor.test", RSAPSS, false)
+	assert.Nil(t, err)
+	tr := ptr.TrustedRoot{
+		MediaType:              "application/vnd.dev.sigstore.trustedroot+json;version=0.1",
+		CertificateAuthorities: []*ptr.CertificateAuthority{ca},
+		Tlogs:                  []*ptr.TransparencyLogInstance{tlog},
+	}
+
+	for _, f := range []string{FormatJSON, FormatYAML, FormatProto} {
+		t.Run(f, func(t *testing.T) {
+			var got ptr.TrustedRoot
+			var out = DefaultOutputOptions
+
+			out.Format = f
+			b, err := marshalCanonical(&tr, out)
+			assert.Nil(t, err)
+			assert.Nil(t, unmarshalProto(b, &got, InputOptions{Format: f}))
+			assert.True(t, proto.Equal(&tr, &got))
+
+			m, err := unmarshalDocument(b, InputOptions{Format: f})
+			assert.Nil(t, err)
+			assert.True(t, proto.Equal(&tr, m))
+		})
+	}
+}
+
+func TestYAMLToJSON(t *testing.T) {
+	b, err := yamlToJSON([]byte(`mediaType: foo
+tlogs:
+  - baseUrl: "123"
+    hashAlgorithm: SHA2_256
+    publicKey:
+      validFor:
+        start: 2024-04-03T00:00:00Z
+`))
+
+	assert.Nil(t, err)
+	assert.Equal(t, `{"mediaType":"foo","tlogs":[{"baseUrl":"123","hashAlgorithm":"SHA2_256","publicKey":{"validFor":{"start":"2024-04-03T00:00:00Z"}}}]}`, string(b))
+}
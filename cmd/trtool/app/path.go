@@ -0,0 +1,466 @@
+package app
+
+import (
+	"bytes"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"google.golang.org/protobuf/encoding/protojson"
+	"google.golang.org/protobuf/reflect/protoreflect"
+	"google.golang.org/protobuf/reflect/protoregistry"
+)
+
+// ErrNotSet is returned when a path resolves to a field without a
+// value.
+var ErrNotSet = errors.New("not set")
+
+// fieldAliases are alternative names accepted when a field is not
+// found, so CAs and logs can be selected with the same key.
+var fieldAliases = map[string]protoreflect.Name{
+	"uri": "base_url",
+	"url": "base_url",
+}
+
+// pathSegment is a field name followed by zero or more selectors,
+// e.g. tlogs[baseUrl=https://rekor.example][0].
+type pathSegment struct {
+	name      string
+	selectors []pathSelector
+}
+
+// pathSelector selects list elements, either by position or by
+// comparing a (possibly nested) field with a value.
+type pathSelector struct {
+	index int
+	key   string
+	value string
+}
+
+// pathTarget is the field a path resolves to. If the field is a list
+// and index is not negative, a single element is referred.
+type pathTarget struct {
+	parent protoreflect.Message
+	fd     protoreflect.FieldDescriptor
+	index  int
+}
+
+// parsePath splits a path like
+// tlogs[uri=https://rekor.example].publicKey.validFor.end into its
+// segments. Dots within a selector are part of the selector.
+func parsePath(p string) ([]pathSegment, error) {
+	var segs []pathSegment
+	var cur pathSegment
+	var name strings.Builder
+
+	for i := 0; i < len(p); i++ {
+		switch p[i] {
+		case '.':
+			if name.Len() == 0 && cur.name == "" {
+				return nil, fmt.Errorf("empty field name at position %d", i)
+			}
+			if cur.name == "" {
+				cur.name = name.String()
+			}
+			segs = append(segs, cur)
+			cur = pathSegment{}
+			name.Reset()
+		case '[':
+			end := strings.IndexByte(p[i:], ']')
+			if end < 0 {
+				return nil, fmt.Errorf("unterminated selector at position %d", i)
+			}
+			if cur.name == "" {
+				if name.Len() == 0 {
+					return nil, fmt.Errorf("selector without field at position %d", i)
+				}
+				cur.name = name.String()
+			}
+			sel, err := parseSelector(p[i+1 : i+end])
+			if err != nil {
+				return nil, err
+			}
+			cur.selectors = append(cur.selectors, sel)
+			i += end
+		default:
+			if cur.name != "" {
+				return nil, fmt.Errorf("unexpected %q after selector at position %d", p[i], i)
+			}
+			name.WriteByte(p[i])
+		}
+	}
+	if cur.name == "" {
+		if name.Len() == 0 {
+			return nil, errors.New("empty path or trailing dot")
+		}
+		cur.name = name.String()
+	}
+
+	return append(segs, cur), nil
+}
+
+func parseSelector(s string) (pathSelector, error) {
+	if k, v, ok := strings.Cut(s, "="); ok {
+		if k == "" {
+			return pathSelector{}, fmt.Errorf("empty key in selector [%s]", s)
+		}
+		return pathSelector{index: -1, key: k, value: v}, nil
+	}
+
+	i, err := strconv.Atoi(s)
+	if err != nil || i < 0 {
+		return pathSelector{}, fmt.Errorf("invalid selector [%s], expected an index or key=value", s)
+	}
+
+	return pathSelector{index: i}, nil
+}
+
+func findField(md protoreflect.MessageDescriptor, name string) protoreflect.FieldDescriptor {
+	fields := md.Fields()
+
+	if fd := fields.ByJSONName(name); fd != nil {
+		return fd
+	}
+	if fd := fields.ByName(protoreflect.Name(name)); fd != nil {
+		return fd
+	}
+	if alias, ok := fieldAliases[name]; ok {
+		return fields.ByName(alias)
+	}
+
+	return nil
+}
+
+// resolvePath walks the message along the path. If create is true,
+// unset messages along the path are allocated so the target can be
+// written to.
+func resolvePath(m protoreflect.Message, segs []pathSegment, create bool) (*pathTarget, error) {
+	var cur = m
+
+	for i, seg := range segs {
+		var last = i == len(segs)-1
+		var index = -1
+
+		fd := findField(cur.Descriptor(), seg.name)
+		if fd == nil {
+			return nil, fmt.Errorf("unknown field %s in %s",
+				seg.name, cur.Descriptor().FullName())
+		}
+		if fd.IsMap() {
+			return nil, fmt.Errorf("map field %s is not supported", seg.name)
+		}
+
+		if fd.IsList() {
+			var err error
+			if index, err = selectElement(cur.Get(fd).List(), seg); err != nil {
+				return nil, err
+			}
+		} else if len(seg.selectors) > 0 {
+			return nil, fmt.Errorf("selector on %s which is not a list", seg.name)
+		}
+
+		if last {
+			return &pathTarget{parent: cur, fd: fd, index: index}, nil
+		}
+
+		if fd.Kind() != protoreflect.MessageKind {
+			return nil, fmt.Errorf("%s is not a message", seg.name)
+		}
+		switch {
+		case fd.IsList() && index < 0:
+			return nil, fmt.Errorf("%s is a list, a selector is required", seg.name)
+		case fd.IsList():
+			cur = cur.Get(fd).List().Get(index).Message()
+		case create:
+			cur = cur.Mutable(fd).Message()
+		case !cur.Has(fd):
+			return nil, fmt.Errorf("%s: %w", seg.name, ErrNotSet)
+		default:
+			cur = cur.Get(fd).Message()
+		}
+	}
+
+	return nil, errors.New("empty path")
+}
+
+// selectElement applies the selectors in order, returns -1 if there
+// are no selectors.
+func selectElement(l protoreflect.List, seg pathSegment) (int, error) {
+	var candidates = make([]int, l.Len())
+
+	if len(seg.selectors) == 0 {
+		return -1, nil
+	}
+
+	for i := range candidates {
+		candidates[i] = i
+	}
+	for _, sel := range seg.selectors {
+		if sel.index >= 0 {
+			if sel.index >= len(candidates) {
+				return 0, fmt.Errorf("index %d out of range for %s with %d elements",
+					sel.index, seg.name, len(candidates))
+			}
+			candidates = []int{candidates[sel.index]}
+			continue
+		}
+
+		keySegs, err := parsePath(sel.key)
+		if err != nil {
+			return 0, fmt.Errorf("invalid selector key %s: %w", sel.key, err)
+		}
+		var matches []int
+		for _, c := range candidates {
+			v := l.Get(c)
+			m, ok := v.Interface().(protoreflect.Message)
+			if !ok {
+				return 0, fmt.Errorf("key selector on %s which is not a list of messages", seg.name)
+			}
+			t, err := resolvePath(m, keySegs, false)
+			if errors.Is(err, ErrNotSet) {
+				continue
+			}
+			if err != nil {
+				return 0, err
+			}
+			s, err := t.format()
+			if err != nil {
+				return 0, err
+			}
+			if s == sel.value {
+				matches = append(matches, c)
+			}
+		}
+		candidates = matches
+	}
+
+	switch len(candidates) {
+	case 0:
+		return 0, fmt.Errorf("no element in %s matches the selector", seg.name)
+	case 1:
+		return candidates[0], nil
+	default:
+		return 0, fmt.Errorf("%d elements in %s match the selector, add an index selector",
+			len(candidates), seg.name)
+	}
+}
+
+// format returns the value of the target. Strings, enums, bytes and
+// timestamps are returned verbatim, messages and lists as JSON.
+func (t *pathTarget) format() (string, error) {
+	var fd = t.fd
+
+	if fd.IsList() && t.index < 0 {
+		var buf bytes.Buffer
+		var l = t.parent.Get(fd).List()
+
+		buf.WriteByte('[')
+		for i := 0; i < l.Len(); i++ {
+			if i > 0 {
+				buf.WriteByte(',')
+			}
+			b, err := valueJSON(fd, l.Get(i))
+			if err != nil {
+				return "", err
+			}
+			buf.Write(b)
+		}
+		buf.WriteByte(']')
+		return buf.String(), nil
+	}
+
+	var v protoreflect.Value
+	if fd.IsList() {
+		v = t.parent.Get(fd).List().Get(t.index)
+	} else {
+		if fd.Kind() == protoreflect.MessageKind && !t.parent.Has(fd) {
+			return "", fmt.Errorf("%s: %w", fd.JSONName(), ErrNotSet)
+		}
+		v = t.parent.Get(fd)
+	}
+
+	b, err := valueJSON(fd, v)
+	if err != nil {
+		return "", err
+	}
+	// Unquote plain strings, i.e. not messages or lists
+	var s string
+	if err := json.Unmarshal(b, &s); err == nil {
+		return s, nil
+	}
+
+	return string(b), nil
+}
+
+// valueJSON encodes a single (non list) value as JSON, the same way
+// protojson would.
+func valueJSON(fd protoreflect.FieldDescriptor, v protoreflect.Value) ([]byte, error) {
+	switch fd.Kind() {
+	case protoreflect.MessageKind, protoreflect.GroupKind:
+		return marshalCanonical(v.Message().Interface(), OutputOptions{Format: FormatJSON})
+	case protoreflect.EnumKind:
+		if ev := fd.Enum().Values().ByNumber(v.Enum()); ev != nil {
+			return json.Marshal(string(ev.Name()))
+		}
+		return json.Marshal(int32(v.Enum()))
+	case protoreflect.BytesKind:
+		return json.Marshal(base64.StdEncoding.EncodeToString(v.Bytes()))
+	case protoreflect.Int64Kind, protoreflect.Sint64Kind, protoreflect.Sfixed64Kind,
+		protoreflect.Uint64Kind, protoreflect.Fixed64Kind:
+		// protojson encodes 64 bit integers as strings
+		return json.Marshal(v.String())
+	default:
+		return json.Marshal(v.Interface())
+	}
+}
+
+// set parses the provided string according to the target field's
+// type and assigns it.
+func (t *pathTarget) set(s string) error {
+	var fd = t.fd
+
+	if fd.IsList() && t.index < 0 {
+		var elems []json.RawMessage
+		if err := json.Unmarshal([]byte(s), &elems); err != nil {
+			return fmt.Errorf("%s is a list, expected a JSON array: %w",
+				fd.JSONName(), err)
+		}
+		l := t.parent.NewField(fd).List()
+		for i, raw := range elems {
+			var elem string
+			if fd.Kind() == protoreflect.MessageKind {
+				elem = string(raw)
+			} else if err := json.Unmarshal(raw, &elem); err != nil {
+				// Numbers and booleans are kept verbatim
+				elem = string(raw)
+			}
+			v, err := parseValue(fd, elem)
+			if err != nil {
+				return fmt.Errorf("element %d: %w", i, err)
+			}
+			l.Append(v)
+		}
+		t.parent.Set(fd, protoreflect.ValueOfList(l))
+		return nil
+	}
+
+	v, err := parseValue(fd, s)
+	if err != nil {
+		return err
+	}
+	if fd.IsList() {
+		t.parent.Mutable(fd).List().Set(t.index, v)
+	} else {
+		t.parent.Set(fd, v)
+	}
+
+	return nil
+}
+
+// parseValue converts a string to a value of the field's (element)
+// type. Messages, including timestamps, are parsed with protojson,
+// where a plain string is accepted for messages with a string JSON
+// representation.
+func parseValue(fd protoreflect.FieldDescriptor, s string) (protoreflect.Value, error) {
+	switch fd.Kind() {
+	case protoreflect.MessageKind, protoreflect.GroupKind:
+		mt, err := protoregistry.GlobalTypes.FindMessageByName(fd.Message().FullName())
+		if err != nil {
+			return protoreflect.Value{}, err
+		}
+		m := mt.New()
+		if err = protojson.Unmarshal([]byte(s), m.Interface()); err != nil {
+			quoted, _ := json.Marshal(s)
+			m = mt.New()
+			if qerr := protojson.Unmarshal(quoted, m.Interface()); qerr != nil {
+				return protoreflect.Value{}, fmt.Errorf("invalid %s: %w",
+					fd.Message().FullName(), err)
+			}
+		}
+		return protoreflect.ValueOfMessage(m), nil
+	case protoreflect.EnumKind:
+		if ev := fd.Enum().Values().ByName(protoreflect.Name(s)); ev != nil {
+			return protoreflect.ValueOfEnum(ev.Number()), nil
+		}
+		if n, err := strconv.ParseInt(s, 10, 32); err == nil {
+			if ev := fd.Enum().Values().ByNumber(protoreflect.EnumNumber(n)); ev != nil {
+				return protoreflect.ValueOfEnum(ev.Number()), nil
+			}
+		}
+		return protoreflect.Value{}, fmt.Errorf("invalid %s value %s, valid values are %s",
+			fd.Enum().FullName(), s, strings.Join(enumNames(fd.Enum()), ", "))
+	case protoreflect.BytesKind:
+		b, err := base64.StdEncoding.DecodeString(s)
+		if err != nil {
+			return protoreflect.Value{}, fmt.Errorf("invalid base64 for %s: %w",
+				fd.JSONName(), err)
+		}
+		return protoreflect.ValueOfBytes(b), nil
+	case protoreflect.StringKind:
+		return protoreflect.ValueOfString(s), nil
+	case protoreflect.BoolKind:
+		b, err := strconv.ParseBool(s)
+		if err != nil {
+			return protoreflect.Value{}, fmt.Errorf("invalid bool for %s: %w",
+				fd.JSONName(), err)
+		}
+		return protoreflect.ValueOfBool(b), nil
+	case protoreflect.Int32Kind, protoreflect.Sint32Kind, protoreflect.Sfixed32Kind:
+		n, err := strconv.ParseInt(s, 10, 32)
+		if err != nil {
+			return protoreflect.Value{}, fmt.Errorf("invalid int32 for %s: %w",
+				fd.JSONName(), err)
+		}
+		return protoreflect.ValueOfInt32(int32(n)), nil
+	case protoreflect.Int64Kind, protoreflect.Sint64Kind, protoreflect.Sfixed64Kind:
+		n, err := strconv.ParseInt(s, 10, 64)
+		if err != nil {
+			return protoreflect.Value{}, fmt.Errorf("invalid int64 for %s: %w",
+				fd.JSONName(), err)
+		}
+		return protoreflect.ValueOfInt64(n), nil
+	case protoreflect.Uint32Kind, protoreflect.Fixed32Kind:
+		n, err := strconv.ParseUint(s, 10, 32)
+		if err != nil {
+			return protoreflect.Value{}, fmt.Errorf("invalid uint32 for %s: %w",
+				fd.JSONName(), err)
+		}
+		return protoreflect.ValueOfUint32(uint32(n)), nil
+	case protoreflect.Uint64Kind, protoreflect.Fixed64Kind:
+		n, err := strconv.ParseUint(s, 10, 64)
+		if err != nil {
+			return protoreflect.Value{}, fmt.Errorf("invalid uint64 for %s: %w",
+				fd.JSONName(), err)
+		}
+		return protoreflect.ValueOfUint64(n), nil
+	case protoreflect.FloatKind:
+		f, err := strconv.ParseFloat(s, 32)
+		if err != nil {
+			return protoreflect.Value{}, fmt.Errorf("invalid float for %s: %w",
+				fd.JSONName(), err)
+		}
+		return protoreflect.ValueOfFloat32(float32(f)), nil
+	case protoreflect.DoubleKind:
+		f, err := strconv.ParseFloat(s, 64)
+		if err != nil {
+			return protoreflect.Value{}, fmt.Errorf("invalid double for %s: %w",
+				fd.JSONName(), err)
+		}
+		return protoreflect.ValueOfFloat64(f), nil
+	default:
+		return protoreflect.Value{}, fmt.Errorf("unsupported field type %s", fd.Kind())
+	}
+}
+
+func enumNames(ed protoreflect.EnumDescriptor) []string {
+	var names []string
+
+	for i := 0; i < ed.Values().Len(); i++ {
+		names = append(names, string(ed.Values().Get(i).Name()))
+	}
+
+	return names
+}
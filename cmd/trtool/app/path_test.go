@@ -0,0 +1,85 @@
+package app
+
+import (
+	"errors"
+	"testing"
+
+	ptr "github.com/sigstore/protobuf-specs/gen/pb-go/trustroot/v1"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParsePath(t *testing.T) {
+	segs, err := parsePath("tlogs[uri=https://rekor.example][1].publicKey.validFor.end")
+
+	assert.Nil(t, err)
+	assert.Equal(t, []pathSegment{
+		{name: "tlogs", selectors: []pathSelector{
+			{index: -1, key: "uri", value: "https://rekor.example"},
+			{index: 1},
+		}},
+		{name: "publicKey"},
+		{name: "validFor"},
+		{name: "end"},
+	}, segs)
+
+	for _, p := range []string{"", "tlogs.", ".tlogs", "tlogs[0", "tlogs[x]", "[0]", "tlogs[0]x"} {
+		_, err = parsePath(p)
+		assert.NotNil(t, err, p)
+	}
+}
+
+func TestGetSetPath(t *testing.T) {
+	var tr ptr.TrustedRoot
+	var rekor = "https://rekor.example"
+
+	for _, u := range []string{"https://old.example", rekor} {
+		tlog, err := newTLog("../../../test_data/rekor.pkix.pem",
+			"2024-04-03T00:00:00Z", "", u, RSAPKCS1v15, false)
+		assert.Nil(t, err)
+		tr.Tlogs = append(tr.Tlogs, tlog)
+	}
+
+	v, err := getPath(&tr, "tlogs[uri=https://rekor.example].publicKey.keyDetails")
+	assert.Nil(t, err)
+	assert.Equal(t, "PKIX_RSA_PKCS1V15_2048_SHA256", v)
+
+	v, err = getPath(&tr, "tlogs[1].log_id.keyId")
+	assert.Nil(t, err)
+	assert.Equal(t, "/TKbCUU9CPkeXPLkZSBMayyIieby0t5s3hpm/mWvTDU=", v)
+
+	_, err = getPath(&tr, "tlogs[uri=https://rekor.example].publicKey.validFor.end")
+	assert.True(t, errors.Is(err, ErrNotSet))
+	_, err = getPath(&tr, "tlogs[logId.keyId=/TKbCUU9CPkeXPLkZSBMayyIieby0t5s3hpm/mWvTDU=].baseUrl")
+	assert.NotNil(t, err, "ambiguous selector")
+	_, err = getPath(&tr, "tlogs[uri=https://none.example].baseUrl")
+	assert.NotNil(t, err)
+	_, err = getPath(&tr, "tlogs.baseUrl")
+	assert.NotNil(t, err)
+
+	// Timestamps
+	err = setPath(&tr, "tlogs[uri=https://rekor.example].publicKey.validFor.end", "2025-01-01T00:00:00Z")
+	assert.Nil(t, err)
+	assert.Equal(t, int64(1735689600), tr.Tlogs[1].PublicKey.ValidFor.End.Seconds)
+	v, err = getPath(&tr, "tlogs[1].publicKey.validFor.end")
+	assert.Nil(t, err)
+	assert.Equal(t, "2025-01-01T00:00:00Z", v)
+	assert.NotNil(t, setPath(&tr, "tlogs[1].publicKey.validFor.end", "tomorrow"))
+
+	// Enums
+	assert.Nil(t, setPath(&tr, "tlogs[1].publicKey.keyDetails", "PKIX_RSA_PSS_2048_SHA256"))
+	assert.Equal(t, "PKIX_RSA_PSS_2048_SHA256", tr.Tlogs[1].PublicKey.KeyDetails.String())
+	assert.NotNil(t, setPath(&tr, "tlogs[1].publicKey.keyDetails", "RSA"))
+
+	// Bytes
+	assert.Nil(t, setPath(&tr, "tlogs[1].logId.keyId", "AAEC"))
+	assert.Equal(t, []byte{0, 1, 2}, tr.Tlogs[1].LogId.KeyId)
+	assert.NotNil(t, setPath(&tr, "tlogs[1].logId.keyId", "not base64!"))
+
+	// Messages and lists
+	assert.Nil(t, setPath(&tr, "certificateAuthorities", `[{"uri":"https://ca.example"}]`))
+	assert.Equal(t, "https://ca.example", tr.CertificateAuthorities[0].Uri)
+	assert.Nil(t, setPath(&tr, "certificateAuthorities[0].subject", `{"commonName":"Root"}`))
+	assert.Equal(t, "Root", tr.CertificateAuthorities[0].Subject.CommonName)
+	assert.NotNil(t, setPath(&tr, "certificateAuthorities[0].subject", `{"cn":"Root"}`))
+	assert.NotNil(t, setPath(&tr, "unknown", "x"))
+}
@@ -0,0 +1,669 @@
+package app
+
+import (
+	"bytes"
+	"context"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/rsa"
+	"crypto/sha1"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/hex"
+	"flag"
+	"fmt"
+	htemplate "html/template"
+	"io"
+	"os"
+	"sort"
+	"strings"
+	ttemplate "text/template"
+	"time"
+
+	"github.com/peterbourgon/ff/v3/ffcli"
+	pc "github.com/sigstore/protobuf-specs/gen/pb-go/common/v1"
+	ptr "github.com/sigstore/protobuf-specs/gen/pb-go/trustroot/v1"
+	"google.golang.org/protobuf/types/known/timestamppb"
+)
+
+const (
+	ReportMarkdown = "md"
+	ReportHTML     = "html"
+)
+
+func Report() *ffcli.Command {
+	var (
+		flagset = flag.NewFlagSet("trtool report", flag.ExitOnError)
+		root    = flagset.String("f", "", "Trusted root to report on")
+		format  = flagset.String("format", ReportMarkdown, "Output format, md or html")
+		in      = addInputFlags(flagset)
+	)
+
+	return &ffcli.Command{
+		Name:       "report",
+		ShortUsage: "trtool report -f file.json -format md|html",
+		ShortHelp:  "Generate an audit report for a trusted root",
+		LongHelp:   "Generate a self-contained audit report for a trusted root, listing all entities, certificate and key details, a timeline and the verification findings",
+		FlagSet:    flagset,
+		Exec: func(ctx context.Context, args []string) error {
+			if *root == "" {
+				return flag.ErrHelp
+			}
+			if *format != ReportMarkdown && *format != ReportHTML {
+				return fmt.Errorf("invalid format %s: %w", *format, flag.ErrHelp)
+			}
+
+			b, err := os.ReadFile(*root)
+			if err != nil {
+				return err
+			}
+
+			return ReportCmd(os.Stdout, b, *in, *format, time.Now())
+		},
+	}
+}
+
+// ReportCmd writes an audit report of the trusted root in b to w.
+// The status of each entity is evaluated at the provided time.
+func ReportCmd(w io.Writer, b []byte, in InputOptions, format string, now time.Time) error {
+	var tr ptr.TrustedRoot
+	var err error
+
+	if err = unmarshalProto(b, &tr, in); err != nil {
+		return fmt.Errorf("failed to unmarhsal trusted root: %w", err)
+	}
+
+	r, err := newReport(&tr, now)
+	if err != nil {
+		return err
+	}
+
+	switch format {
+	case ReportMarkdown:
+		t := ttemplate.Must(ttemplate.New("md").Funcs(ttemplate.FuncMap{
+			"cell": mdCell,
+		}).Parse(mdReport))
+		return t.Execute(w, r)
+	case ReportHTML:
+		t := htemplate.Must(htemplate.New("html").Parse(htmlReport))
+		return t.Execute(w, r)
+	default:
+		return fmt.Errorf("unsupported report format %s", format)
+	}
+}
+
+type report struct {
+	MediaType   string
+	Generated   string
+	Inventory   []reportEntity
+	CAs         []reportCA
+	TSAs        []reportCA
+	TLogs       []reportLog
+	CTLogs      []reportLog
+	Timeline    []reportEvent
+	Valid       bool
+	Findings    string
+	EntityCount int
+}
+
+type reportEntity struct {
+	Type   string
+	URI    string
+	Name   string
+	Start  string
+	End    string
+	Status string
+}
+
+type reportCA struct {
+	reportEntity
+	Certificates []reportCert
+}
+
+type reportCert struct {
+	Position   string
+	Subject    string
+	Issuer     string
+	Serial     string
+	NotBefore  string
+	NotAfter   string
+	IsCA       bool
+	MaxPathLen int
+	KeyUsage   string
+	SAN        string
+	EKU        string
+	Policies   string
+	PublicKey  string
+	SHA256     string
+	SHA1       string
+}
+
+type reportLog struct {
+	reportEntity
+	HashAlgorithm string
+	KeyDetails    string
+	PublicKey     string
+	LogID         string
+	LogIDHex      string
+	SHA256        string
+}
+
+type reportEvent struct {
+	Time   string
+	Event  string
+	Entity string
+	ts     time.Time
+}
+
+func newReport(tr *ptr.TrustedRoot, now time.Time) (*report, error) {
+	var r = report{
+		MediaType: tr.MediaType,
+		Generated: now.UTC().Format(time.RFC3339),
+	}
+	var err error
+
+	if r.CAs, err = reportCAs(tr.CertificateAuthorities, "CA", now); err != nil {
+		return nil, err
+	}
+	if r.TSAs, err = reportCAs(tr.TimestampAuthorities, "TSA", now); err != nil {
+		return nil, err
+	}
+	if r.TLogs, err = reportLogs(tr.Tlogs, "tlog", now); err != nil {
+		return nil, err
+	}
+	if r.CTLogs, err = reportLogs(tr.Ctlogs, "ctlog", now); err != nil {
+		return nil, err
+	}
+
+	for _, ca := range r.CAs {
+		r.Inventory = append(r.Inventory, ca.reportEntity)
+	}
+	for _, ca := range r.TSAs {
+		r.Inventory = append(r.Inventory, ca.reportEntity)
+	}
+	for _, l := range r.TLogs {
+		r.Inventory = append(r.Inventory, l.reportEntity)
+	}
+	for _, l := range r.CTLogs {
+		r.Inventory = append(r.Inventory, l.reportEntity)
+	}
+	r.EntityCount = len(r.Inventory)
+
+	r.Timeline = append(r.Timeline, caEvents(tr.CertificateAuthorities, "CA")...)
+	r.Timeline = append(r.Timeline, caEvents(tr.TimestampAuthorities, "TSA")...)
+	r.Timeline = append(r.Timeline, logEvents(tr.Tlogs, "tlog")...)
+	r.Timeline = append(r.Timeline, logEvents(tr.Ctlogs, "ctlog")...)
+	sort.SliceStable(r.Timeline, func(i, j int) bool {
+		return r.Timeline[i].ts.Before(r.Timeline[j].ts)
+	})
+
+	var findings bytes.Buffer
+	r.Valid = VerifyTrustedRoot(&findings, tr, false)
+	r.Findings = findings.String()
+
+	return &r, nil
+}
+
+func reportCAs(cas []*ptr.CertificateAuthority, kind string, now time.Time) ([]reportCA, error) {
+	var res = make([]reportCA, 0, len(cas))
+
+	for _, ca := range cas {
+		var rca = reportCA{
+			reportEntity: reportEntity{
+				Type:   kind,
+				URI:    ca.Uri,
+				Name:   formatDN(ca.Subject),
+				Start:  formatTime(ca.ValidFor.GetStart()),
+				End:    formatTime(ca.ValidFor.GetEnd()),
+				Status: validityStatus(ca.ValidFor, now),
+			},
+		}
+		certs := ca.GetCertChain().GetCertificates()
+		for i, cert := range certs {
+			c, err := x509.ParseCertificate(cert.RawBytes)
+			if err != nil {
+				return nil, fmt.Errorf("invalid certificate at pos %d for %s: %w",
+					i, ca.Uri, err)
+			}
+			rc := newReportCert(c)
+			switch {
+			case len(certs) == 1:
+				rc.Position = "root"
+			case i == 0:
+				rc.Position = "leaf"
+			case i == len(certs)-1:
+				rc.Position = "root"
+			default:
+				rc.Position = "intermediate"
+			}
+			rca.Certificates = append(rca.Certificates, rc)
+		}
+		res = append(res, rca)
+	}
+
+	return res, nil
+}
+
+func newReportCert(c *x509.Certificate) reportCert {
+	var san []string
+	var eku []string
+	var policies []string
+	s256 := sha256.Sum256(c.Raw)
+	s1 := sha1.Sum(c.Raw)
+
+	san = append(san, prefixAll("DNS:", c.DNSNames)...)
+	san = append(san, prefixAll("email:", c.EmailAddresses)...)
+	for _, ip := range c.IPAddresses {
+		san = append(san, "IP:"+ip.String())
+	}
+	for _, u := range c.URIs {
+		san = append(san, "URI:"+u.String())
+	}
+	for _, u := range c.ExtKeyUsage {
+		eku = append(eku, extKeyUsageName(u))
+	}
+	for _, oid := range c.UnknownExtKeyUsage {
+		eku = append(eku, oid.String())
+	}
+	for _, oid := range c.PolicyIdentifiers {
+		policies = append(policies, oid.String())
+	}
+
+	return reportCert{
+		Subject:    c.Subject.String(),
+		Issuer:     c.Issuer.String(),
+		Serial:     colonHex(c.SerialNumber.Bytes()),
+		NotBefore:  c.NotBefore.UTC().Format(time.RFC3339),
+		NotAfter:   c.NotAfter.UTC().Format(time.RFC3339),
+		IsCA:       c.IsCA,
+		MaxPathLen: c.MaxPathLen,
+		KeyUsage:   strings.Join(keyUsageNames(c.KeyUsage), ", "),
+		SAN:        strings.Join(san, ", "),
+		EKU:        strings.Join(eku, ", "),
+		Policies:   strings.Join(policies, ", "),
+		PublicKey:  describePublicKey(c.PublicKey),
+		SHA256:     colonHex(s256[:]),
+		SHA1:       colonHex(s1[:]),
+	}
+}
+
+func reportLogs(logs []*ptr.TransparencyLogInstance, kind string, now time.Time) ([]reportLog, error) {
+	var res = make([]reportLog, 0, len(logs))
+
+	for _, l := range logs {
+		var desc string
+		pk := l.GetPublicKey()
+		if pub, err := x509.ParsePKIXPublicKey(pk.GetRawBytes()); err == nil {
+			desc = describePublicKey(pub)
+		} else {
+			desc = fmt.Sprintf("unparsable key: %v", err)
+		}
+		s := sha256.Sum256(pk.GetRawBytes())
+
+		res = append(res, reportLog{
+			reportEntity: reportEntity{
+				Type:   kind,
+				URI:    l.BaseUrl,
+				Name:   desc,
+				Start:  formatTime(pk.GetValidFor().GetStart()),
+				End:    formatTime(pk.GetValidFor().GetEnd()),
+				Status: validityStatus(pk.GetValidFor(), now),
+			},
+			HashAlgorithm: l.HashAlgorithm.String(),
+			KeyDetails:    pk.GetKeyDetails().String(),
+			PublicKey:     desc,
+			LogID:         base64.StdEncoding.EncodeToString(l.GetLogId().GetKeyId()),
+			LogIDHex:      hex.EncodeToString(l.GetLogId().GetKeyId()),
+			SHA256:        colonHex(s[:]),
+		})
+	}
+
+	return res, nil
+}
+
+func caEvents(cas []*ptr.CertificateAuthority, kind string) []reportEvent {
+	var res []reportEvent
+
+	for _, ca := range cas {
+		name := fmt.Sprintf("%s %s (%s)", kind, ca.Uri, formatDN(ca.Subject))
+		res = append(res, validityEvents(ca.ValidFor, name)...)
+	}
+
+	return res
+}
+
+func logEvents(logs []*ptr.TransparencyLogInstance, kind string) []reportEvent {
+	var res []reportEvent
+
+	for _, l := range logs {
+		name := fmt.Sprintf("%s %s (%s)", kind, l.BaseUrl,
+			base64.StdEncoding.EncodeToString(l.GetLogId().GetKeyId()))
+		res = append(res, validityEvents(l.GetPublicKey().GetValidFor(), name)...)
+	}
+
+	return res
+}
+
+func validityEvents(tr *pc.TimeRange, name string) []reportEvent {
+	var res []reportEvent
+
+	if tr.GetStart() != nil {
+		res = append(res, reportEvent{
+			Time:   formatTime(tr.Start),
+			Event:  "start",
+			Entity: name,
+			ts:     tr.Start.AsTime(),
+		})
+	}
+	if tr.GetEnd() != nil {
+		res = append(res, reportEvent{
+			Time:   formatTime(tr.End),
+			Event:  "end",
+			Entity: name,
+			ts:     tr.End.AsTime(),
+		})
+	}
+
+	return res
+}
+
+func validityStatus(tr *pc.TimeRange, now time.Time) string {
+	switch {
+	case tr.GetStart() == nil:
+		return "invalid (no start)"
+	case now.Before(tr.Start.AsTime()):
+		return "pending"
+	case tr.GetEnd() != nil && now.After(tr.End.AsTime()):
+		return "expired"
+	default:
+		return "active"
+	}
+}
+
+func formatDN(dn *pc.DistinguishedName) string {
+	return fmt.Sprintf("O=%s, CN=%s", dn.GetOrganization(), dn.GetCommonName())
+}
+
+func formatTime(ts *timestamppb.Timestamp) string {
+	if ts == nil {
+		return ""
+	}
+
+	return ts.AsTime().UTC().Format(time.RFC3339)
+}
+
+func describePublicKey(pub any) string {
+	switch v := pub.(type) {
+	case *ecdsa.PublicKey:
+		return fmt.Sprintf("ECDSA %s", v.Curve.Params().Name)
+	case *rsa.PublicKey:
+		return fmt.Sprintf("RSA %d", v.Size()*8)
+	case ed25519.PublicKey:
+		return "Ed25519"
+	default:
+		return fmt.Sprintf("%T", pub)
+	}
+}
+
+func colonHex(b []byte) string {
+	var parts = make([]string, len(b))
+
+	for i := range b {
+		parts[i] = fmt.Sprintf("%02X", b[i])
+	}
+
+	return strings.Join(parts, ":")
+}
+
+func prefixAll(prefix string, s []string) []string {
+	var res = make([]string, len(s))
+
+	for i := range s {
+		res[i] = prefix + s[i]
+	}
+
+	return res
+}
+
+func keyUsageNames(ku x509.KeyUsage) []string {
+	var names = []struct {
+		ku   x509.KeyUsage
+		name string
+	}{
+		{x509.KeyUsageDigitalSignature, "digitalSignature"},
+		{x509.KeyUsageContentCommitment, "contentCommitment"},
+		{x509.KeyUsageKeyEncipherment, "keyEncipherment"},
+		{x509.KeyUsageDataEncipherment, "dataEncipherment"},
+		{x509.KeyUsageKeyAgreement, "keyAgreement"},
+		{x509.KeyUsageCertSign, "keyCertSign"},
+		{x509.KeyUsageCRLSign, "cRLSign"},
+		{x509.KeyUsageEncipherOnly, "encipherOnly"},
+		{x509.KeyUsageDecipherOnly, "decipherOnly"},
+	}
+	var res []string
+
+	for _, n := range names {
+		if ku&n.ku != 0 {
+			res = append(res, n.name)
+		}
+	}
+
+	return res
+}
+
+func extKeyUsageName(u x509.ExtKeyUsage) string {
+	switch u {
+	case x509.ExtKeyUsageAny:
+		return "any"
+	case x509.ExtKeyUsageServerAuth:
+		return "serverAuth"
+	case x509.ExtKeyUsageClientAuth:
+		return "clientAuth"
+	case x509.ExtKeyUsageCodeSigning:
+		return "codeSigning"
+	case x509.ExtKeyUsageEmailProtection:
+		return "emailProtection"
+	case x509.ExtKeyUsageTimeStamping:
+		return "timeStamping"
+	case x509.ExtKeyUsageOCSPSigning:
+		return "OCSPSigning"
+	default:
+		return fmt.Sprintf("unknown(%d)", u)
+	}
+}
+
+// mdCell escapes a value so it can be put in a markdown table cell.
+func mdCell(s string) string {
+	if s == "" {
+		return "-"
+	}
+	s = strings.ReplaceAll(s, "|", "\\|")
+	return strings.ReplaceAll(s, "\n", " ")
+}
+
+const mdReport = `# Trusted root audit report
+
+* Media type: ` + "`{{ .MediaType }}`" + `
+* Generated: {{ .Generated }}
+* Entities: {{ .EntityCount }}
+* Verification: {{ if .Valid }}valid{{ else }}**FAILED**{{ end }}
+
+## Inventory
+
+| Type | URI | Subject / key | Valid from | Valid to | Status |
+|------|-----|---------------|------------|----------|--------|
+{{- range .Inventory }}
+| {{ .Type }} | {{ cell .URI }} | {{ cell .Name }} | {{ cell .Start }} | {{ cell .End }} | {{ .Status }} |
+{{- end }}
+{{ define "ca" }}
+### {{ .Type }} {{ .URI }}
+
+* Subject: {{ .Name }}
+* Valid from: {{ cell .Start }}
+* Valid to: {{ cell .End }}
+* Status: {{ .Status }}
+{{ range $i, $c := .Certificates }}
+#### Certificate {{ $i }} ({{ $c.Position }})
+
+| Field | Value |
+|-------|-------|
+| Subject | {{ cell $c.Subject }} |
+| Issuer | {{ cell $c.Issuer }} |
+| Serial | {{ cell $c.Serial }} |
+| Not before | {{ $c.NotBefore }} |
+| Not after | {{ $c.NotAfter }} |
+| CA | {{ $c.IsCA }} |
+| Max path length | {{ $c.MaxPathLen }} |
+| Key usage | {{ cell $c.KeyUsage }} |
+| Subject alternative names | {{ cell $c.SAN }} |
+| Extended key usage | {{ cell $c.EKU }} |
+| Policies | {{ cell $c.Policies }} |
+| Public key | {{ cell $c.PublicKey }} |
+| SHA-256 fingerprint | {{ cell $c.SHA256 }} |
+| SHA-1 fingerprint | {{ cell $c.SHA1 }} |
+{{ end }}
+{{- end }}
+{{- define "log" }}
+### {{ .Type }} {{ .URI }}
+
+| Field | Value |
+|-------|-------|
+| Base URL | {{ cell .URI }} |
+| Hash algorithm | {{ .HashAlgorithm }} |
+| Key details | {{ .KeyDetails }} |
+| Public key | {{ cell .PublicKey }} |
+| Log ID (base64) | {{ cell .LogID }} |
+| Log ID (hex) | {{ cell .LogIDHex }} |
+| Key SHA-256 fingerprint | {{ cell .SHA256 }} |
+| Valid from | {{ cell .Start }} |
+| Valid to | {{ cell .End }} |
+| Status | {{ .Status }} |
+{{ end }}
+## Certificate authorities
+{{ range .CAs }}{{ template "ca" . }}{{ else }}
+None
+{{ end }}
+## Timestamp authorities
+{{ range .TSAs }}{{ template "ca" . }}{{ else }}
+None
+{{ end }}
+## Transparency logs
+{{ range .TLogs }}{{ template "log" . }}{{ else }}
+None
+{{ end }}
+## Certificate transparency logs
+{{ range .CTLogs }}{{ template "log" . }}{{ else }}
+None
+{{ end }}
+## Timeline
+
+| Time | Event | Entity |
+|------|-------|--------|
+{{- range .Timeline }}
+| {{ .Time }} | {{ .Event }} | {{ cell .Entity }} |
+{{- end }}
+
+## Verification
+
+Result: {{ if .Valid }}valid{{ else }}**FAILED**{{ end }}
+
+` + "```" + `
+{{ .Findings }}` + "```" + `
+`
+
+const htmlReport = `<!DOCTYPE html>
+<html>
+<head>
+<meta charset="utf-8">
+<title>Trusted root audit report</title>
+<style>
+body { font-family: sans-serif; margin: 2em; }
+table { border-collapse: collapse; margin-bottom: 1em; }
+th, td { border: 1px solid #999; padding: 0.2em 0.5em; text-align: left; vertical-align: top; }
+td { font-family: monospace; word-break: break-all; }
+.failed { color: #b00; font-weight: bold; }
+pre { background: #eee; padding: 1em; }
+</style>
+</head>
+<body>
+<h1>Trusted root audit report</h1>
+<ul>
+<li>Media type: <code>{{ .MediaType }}</code></li>
+<li>Generated: {{ .Generated }}</li>
+<li>Entities: {{ .EntityCount }}</li>
+<li>Verification: {{ if .Valid }}valid{{ else }}<span class="failed">FAILED</span>{{ end }}</li>
+</ul>
+
+<h2>Inventory</h2>
+<table>
+<tr><th>Type</th><th>URI</th><th>Subject / key</th><th>Valid from</th><th>Valid to</th><th>Status</th></tr>
+{{- range .Inventory }}
+<tr><td>{{ .Type }}</td><td>{{ .URI }}</td><td>{{ .Name }}</td><td>{{ .Start }}</td><td>{{ .End }}</td><td>{{ .Status }}</td></tr>
+{{- end }}
+</table>
+{{ define "ca" }}
+<h3>{{ .Type }} {{ .URI }}</h3>
+<ul>
+<li>Subject: {{ .Name }}</li>
+<li>Valid from: {{ .Start }}</li>
+<li>Valid to: {{ .End }}</li>
+<li>Status: {{ .Status }}</li>
+</ul>
+{{ range $i, $c := .Certificates }}
+<h4>Certificate {{ $i }} ({{ $c.Position }})</h4>
+<table>
+<tr><th>Subject</th><td>{{ $c.Subject }}</td></tr>
+<tr><th>Issuer</th><td>{{ $c.Issuer }}</td></tr>
+<tr><th>Serial</th><td>{{ $c.Serial }}</td></tr>
+<tr><th>Not before</th><td>{{ $c.NotBefore }}</td></tr>
+<tr><th>Not after</th><td>{{ $c.NotAfter }}</td></tr>
+<tr><th>CA</th><td>{{ $c.IsCA }}</td></tr>
+<tr><th>Max path length</th><td>{{ $c.MaxPathLen }}</td></tr>
+<tr><th>Key usage</th><td>{{ $c.KeyUsage }}</td></tr>
+<tr><th>Subject alternative names</th><td>{{ $c.SAN }}</td></tr>
+<tr><th>Extended key usage</th><td>{{ $c.EKU }}</td></tr>
+<tr><th>Policies</th><td>{{ $c.Policies }}</td></tr>
+<tr><th>Public key</th><td>{{ $c.PublicKey }}</td></tr>
+<tr><th>SHA-256 fingerprint</th><td>{{ $c.SHA256 }}</td></tr>
+<tr><th>SHA-1 fingerprint</th><td>{{ $c.SHA1 }}</td></tr>
+</table>
+{{ end }}
+{{- end }}
+{{- define "log" }}
+<h3>{{ .Type }} {{ .URI }}</h3>
+<table>
+<tr><th>Base URL</th><td>{{ .URI }}</td></tr>
+<tr><th>Hash algorithm</th><td>{{ .HashAlgorithm }}</td></tr>
+<tr><th>Key details</th><td>{{ .KeyDetails }}</td></tr>
+<tr><th>Public key</th><td>{{ .PublicKey }}</td></tr>
+<tr><th>Log ID (base64)</th><td>{{ .LogID }}</td></tr>
+<tr><th>Log ID (hex)</th><td>{{ .LogIDHex }}</td></tr>
+<tr><th>Key SHA-256 fingerprint</th><td>{{ .SHA256 }}</td></tr>
+<tr><th>Valid from</th><td>{{ .Start }}</td></tr>
+<tr><th>Valid to</th><td>{{ .End }}</td></tr>
+<tr><th>Status</th><td>{{ .Status }}</td></tr>
+</table>
+{{ end }}
+<h2>Certificate authorities</h2>
+{{ range .CAs }}{{ template "ca" . }}{{ else }}<p>None</p>{{ end }}
+<h2>Timestamp authorities</h2>
+{{ range .TSAs }}{{ template "ca" . }}{{ else }}<p>None</p>{{ end }}
+<h2>Transparency logs</h2>
+{{ range .TLogs }}{{ template "log" . }}{{ else }}<p>None</p>{{ end }}
+<h2>Certificate transparency logs</h2>
+{{ range .CTLogs }}{{ template "log" . }}{{ else }}<p>None</p>{{ end }}
+<h2>Timeline</h2>
+<table>
+<tr><th>Time</th><th>Event</th><th>Entity</th></tr>
+{{- range .Timeline }}
+<tr><td>{{ .Time }}</td><td>{{ .Event }}</td><td>{{ .Entity }}</td></tr>
+{{- end }}
+</table>
+
+<h2>Verification</h2>
+<p>Result: {{ if .Valid }}valid{{ else }}<span class="failed">FAILED</span>{{ end }}</p>
+<pre>{{ .Findings }}</pre>
+</body>
+</html>
+`
@@ -0,0 +1,43 @@
+package app
+
+import (
+	"bytes"
+	"testing"
+	"time"
+
+	ptr "github.com/sigstore/protobuf-specs/gen/pb-go/trustroot/v1"
+	"github.com/stretchr/testify/assert"
+	"google.golang.org/protobuf/encoding/protojson"
+)
+
+func TestReport(t *testing.T) {
+	ca, err := newCertificateAuthority("../../../test_data/fulcio-chain.pem",
+		"2024-04-03T00:00:00Z", "", "https://fulcio.test", false)
+	assert.Nil(t, err)
+	tlog, err := newTLog("../../../test_data/rekor.pkix.pem",
+		"2024-04-03T00:00:00Z", "2024-05-01T00:00:00Z", "https://rekor.test",
+		RSAPKCS1v15, false)
+	assert.Nil(t, err)
+	tr := ptr.TrustedRoot{
+		MediaType:              "application/vnd.dev.sigstore.trustedroot+json;version=0.1",
+		CertificateAuthorities: []*ptr.CertificateAuthority{ca},
+		Tlogs:                  []*ptr.TransparencyLogInstance{tlog},
+	}
+	b, err := protojson.Marshal(&tr)
+	assert.Nil(t, err)
+	now := time.Date(2024, 6, 1, 0, 0, 0, 0, time.UTC)
+
+	var md bytes.Buffer
+	assert.Nil(t, ReportCmd(&md, b, DefaultInputOptions, ReportMarkdown, now))
+	assert.Contains(t, md.String(), "| CA | https://fulcio.test | O=Umbrella Corporation, CN=Root | 2024-04-03T00:00:00Z | - | active |")
+	assert.Contains(t, md.String(), "| tlog | https://rekor.test | RSA 2048 | 2024-04-03T00:00:00Z | 2024-05-01T00:00:00Z | expired |")
+	assert.Contains(t, md.String(), "#### Certificate 0 (leaf)")
+	assert.Contains(t, md.String(), "| Extended key usage | codeSigning |")
+	assert.Contains(t, md.String(), "| 2024-05-01T00:00:00Z | end | tlog https://rekor.test")
+	assert.Contains(t, md.String(), "Result: valid")
+
+	var html bytes.Buffer
+	assert.Nil(t, ReportCmd(&html, b, DefaultInputOptions, ReportHTML, now))
+	assert.Contains(t, html.String(), "<td>https://fulcio.test</td>")
+	assert.Contains(t, html.String(), "<p>Result: valid</p>")
+}
@@ -0,0 +1,325 @@
+package app
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"time"
+
+	"github.com/peterbourgon/ff/v3/ffcli"
+	ptr "github.com/sigstore/protobuf-specs/gen/pb-go/trustroot/v1"
+	"google.golang.org/protobuf/types/known/timestamppb"
+)
+
+// TypeOIDC is the OIDC provider service type of a signing config, the
+// other services use the trusted root types.
+const TypeOIDC = "oidc"
+
+func SCAdd() *ffcli.Command {
+	var (
+		flagset  = flag.NewFlagSet("trtool sc-add", flag.ExitOnError)
+		file     = flagset.String("f", "signing_config.json", "Signing config to update")
+		sType    = flagset.String("type", "", "The service type, ca, oidc, tlog or tsa")
+		service  = flagset.String("service", "", "The service to add")
+		defaults = addServiceFlags(flagset)
+		in       = addInputFlags(flagset)
+		out      = addOutputFlags(flagset)
+	)
+
+	return &ffcli.Command{
+		Name:       "sc-add",
+		ShortUsage: "trtool sc-add -f signing_config.json -type tsa -service https://tsa.example",
+		ShortHelp:  "Add a service to a signing config",
+		LongHelp: `Add a service to a signing config, before the existing services of
+the same type as services are ordered newest first. Use sc-set to replace
+a service.
+` + serviceHelp,
+		FlagSet: flagset,
+		Exec: func(ctx context.Context, args []string) error {
+			if *service == "" {
+				return fmt.Errorf("no service provided: %w", flag.ErrHelp)
+			}
+
+			return SCAddCmd(*file, *sType, *service, *defaults, *in, *out)
+		},
+	}
+}
+
+func SCRemove() *ffcli.Command {
+	var (
+		flagset = flag.NewFlagSet("trtool sc-remove", flag.ExitOnError)
+		file    = flagset.String("f", "signing_config.json", "Signing config to update")
+		sType   = flagset.String("type", "", "The service type, ca, oidc, tlog or tsa")
+		url     = flagset.String("url", "", "URL of the service to remove")
+		in      = addInputFlags(flagset)
+		out     = addOutputFlags(flagset)
+	)
+
+	return &ffcli.Command{
+		Name:       "sc-remove",
+		ShortUsage: "trtool sc-remove -f signing_config.json -type tlog -url https://rekor.example",
+		ShortHelp:  "Remove a service from a signing config",
+		LongHelp:   "Remove all services of a type with the URL from a signing config",
+		FlagSet:    flagset,
+		Exec: func(ctx context.Context, args []string) error {
+			if *url == "" {
+				return fmt.Errorf("no url provided: %w", flag.ErrHelp)
+			}
+
+			return SCRemoveCmd(*file, *sType, *url, *in, *out)
+		},
+	}
+}
+
+func SCSet() *ffcli.Command {
+	var (
+		flagset  = flag.NewFlagSet("trtool sc-set", flag.ExitOnError)
+		file     = flagset.String("f", "signing_config.json", "Signing config to update")
+		sType    = flagset.String("type", "", "The service type, ca, oidc, tlog or tsa")
+		service  = flagset.String("service", "", "The new service")
+		url      = flagset.String("url", "", "URL of the service to replace. Defaults to all open services with the same API version")
+		defaults = addServiceFlags(flagset)
+		prevEnd  = flagset.String("prev-end", "", "End time for the replaced services. Defaults to the start of the new service")
+		selector = flagset.String("selector", "", "New selector for tlog or tsa, ALL, ANY or EXACT")
+		count    = flagset.Uint("count", 0, "Number of services to use with -selector EXACT")
+		in       = addInputFlags(flagset)
+		out      = addOutputFlags(flagset)
+	)
+
+	return &ffcli.Command{
+		Name:       "sc-set",
+		ShortUsage: "trtool sc-set -f signing_config.json -type ca -service https://fulcio.example",
+		ShortHelp:  "Replace a service in a signing config",
+		LongHelp: `Replace a service in a signing config. The validity period of the
+replaced services is closed, like add does for a trusted root. Version 0.1
+signing configs have no validity periods, there the replaced services are
+removed. -selector updates the selector of the tlogs or TSAs, with or
+without a new service.
+` + serviceHelp,
+		FlagSet: flagset,
+		Exec: func(ctx context.Context, args []string) error {
+			if *service == "" && *selector == "" {
+				return fmt.Errorf("no service or selector provided: %w", flag.ErrHelp)
+			}
+
+			return SCSetCmd(*file, *sType, *service, *url, *prevEnd, *defaults,
+				*selector, *count, *in, *out)
+		},
+	}
+}
+
+func SCAddCmd(p, sType, service string, defaults ServiceDefaults, in InputOptions, out OutputOptions) error {
+	sc, err := readSigningConfig(p, in)
+	if err != nil {
+		return err
+	}
+
+	if err = addService(sc, sType, service, defaults); err != nil {
+		return err
+	}
+
+	return printProto(sc, out)
+}
+
+func SCRemoveCmd(p, sType, url string, in InputOptions, out OutputOptions) error {
+	sc, err := readSigningConfig(p, in)
+	if err != nil {
+		return err
+	}
+
+	if err = removeService(sc, sType, url); err != nil {
+		return err
+	}
+
+	return printProto(sc, out)
+}
+
+func SCSetCmd(p, sType, service, url, prevEnd string, defaults ServiceDefaults,
+	selector string, count uint, in InputOptions, out OutputOptions) error {
+	var prevEndTs time.Time
+
+	if prevEnd != "" {
+		var err error
+		if prevEndTs, err = time.Parse(time.RFC3339, prevEnd); err != nil {
+			return fmt.Errorf("invalid prev-end %s: %w", prevEnd, err)
+		}
+	}
+
+	sc, err := readSigningConfig(p, in)
+	if err != nil {
+		return err
+	}
+
+	if service != "" {
+		if err = replaceService(sc, sType, service, url, defaults, prevEndTs); err != nil {
+			return err
+		}
+	}
+	if selector != "" {
+		if err = setSelector(sc, sType, selector, count); err != nil {
+			return err
+		}
+	}
+
+	return printProto(sc, out)
+}
+
+// scServices returns the services of a type.
+func scServices(sc *ptr.SigningConfig, sType string) (*[]*ptr.Service, error) {
+	switch sType {
+	case TypeCA:
+		return &sc.CaUrls, nil
+	case TypeOIDC:
+		return &sc.OidcUrls, nil
+	case TypeTLog:
+		return &sc.RekorTlogUrls, nil
+	case TypeTSA:
+		return &sc.TsaUrls, nil
+	}
+
+	return nil, fmt.Errorf("invalid service type %q, expected ca, oidc, tlog or tsa", sType)
+}
+
+// scConfig returns the selector configuration of a type, or nil for
+// types without one.
+func scConfig(sc *ptr.SigningConfig, sType string) **ptr.ServiceConfiguration {
+	switch sType {
+	case TypeTLog:
+		return &sc.RekorTlogConfig
+	case TypeTSA:
+		return &sc.TsaConfig
+	}
+
+	return nil
+}
+
+// newScService parses a service to add to the signing config.
+func newScService(sc *ptr.SigningConfig, spec string, defaults ServiceDefaults) (*ptr.Service, error) {
+	s, err := parseService(spec)
+	if err != nil {
+		return nil, err
+	}
+	if !isSigningConfigV01(sc) {
+		if err = defaults.apply(s); err != nil {
+			return nil, err
+		}
+	}
+
+	return s, nil
+}
+
+func addService(sc *ptr.SigningConfig, sType, spec string, defaults ServiceDefaults) error {
+	svcs, err := scServices(sc, sType)
+	if err != nil {
+		return err
+	}
+	s, err := newScService(sc, spec, defaults)
+	if err != nil {
+		return err
+	}
+
+	// Services are ordered newest first
+	*svcs = append([]*ptr.Service{s}, *svcs...)
+	if c := scConfig(sc, sType); c != nil && *c == nil {
+		*c = &ptr.ServiceConfiguration{Selector: ptr.ServiceSelector_ALL}
+	}
+
+	return nil
+}
+
+func removeService(sc *ptr.SigningConfig, sType, url string) error {
+	svcs, err := scServices(sc, sType)
+	if err != nil {
+		return err
+	}
+
+	var kept []*ptr.Service
+	for _, s := range *svcs {
+		if s.GetUrl() != url {
+			kept = append(kept, s)
+		}
+	}
+	if len(kept) == len(*svcs) {
+		return fmt.Errorf("no %s service with URL %s", sType, url)
+	}
+
+	*svcs = kept
+	if c := scConfig(sc, sType); c != nil && len(kept) == 0 {
+		*c = nil
+	}
+
+	return nil
+}
+
+// replaceService adds a service and closes the validity period of the
+// services it replaces: the ones with the URL, or if no URL is given,
+// the open ones with the same API version. The period ends at prevEnd,
+// or the start of the new service if zero. Replaced services must have
+// a start.
+func replaceService(sc *ptr.SigningConfig, sType, spec, url string, defaults ServiceDefaults, prevEnd time.Time) error {
+	svcs, err := scServices(sc, sType)
+	if err != nil {
+		return err
+	}
+	s, err := newScService(sc, spec, defaults)
+	if err != nil {
+		return err
+	}
+
+	var end = s.ValidFor.GetStart()
+	if !prevEnd.IsZero() {
+		end = timestamppb.New(prevEnd)
+	}
+
+	var kept []*ptr.Service
+	var replaced int
+	for _, old := range *svcs {
+		var match bool
+		if url != "" {
+			match = old.GetUrl() == url
+		} else {
+			match = old.ValidFor.GetEnd() == nil &&
+				old.GetMajorApiVersion() == s.GetMajorApiVersion()
+		}
+		if !match {
+			kept = append(kept, old)
+			continue
+		}
+		replaced++
+		if isSigningConfigV01(sc) {
+			continue
+		}
+		if old.ValidFor.GetStart() == nil {
+			return fmt.Errorf("%s service %s has no validity start, an end alone is not valid", sType, old.GetUrl())
+		}
+		if old.ValidFor.GetEnd() == nil {
+			old.ValidFor.End = end
+		}
+		kept = append(kept, old)
+	}
+	if url != "" && replaced == 0 {
+		return fmt.Errorf("no %s service with URL %s", sType, url)
+	}
+
+	*svcs = append([]*ptr.Service{s}, kept...)
+	if c := scConfig(sc, sType); c != nil && *c == nil {
+		*c = &ptr.ServiceConfiguration{Selector: ptr.ServiceSelector_ALL}
+	}
+
+	return nil
+}
+
+func setSelector(sc *ptr.SigningConfig, sType, selector string, count uint) error {
+	c := scConfig(sc, sType)
+	if c == nil {
+		return fmt.Errorf("selectors are only supported for tlog and tsa, not %s", sType)
+	}
+
+	conf, err := newServiceConfig(selector, count)
+	if err != nil {
+		return err
+	}
+	*c = conf
+
+	return nil
+}
@@ -0,0 +1,44 @@
+package app
+
+import (
+	"testing"
+	"time"
+
+	ptr "github.com/sigstore/protobuf-specs/gen/pb-go/trustroot/v1"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestReplaceService(t *testing.T) {
+	var defaults = ServiceDefaults{Start: "2024-01-01T00:00:00Z", Operator: "test.com"}
+	var sc = ptr.SigningConfig{
+		MediaType: "application/vnd.dev.sigstore.signingconfig.v0.2+json",
+	}
+
+	assert.Nil(t, addService(&sc, TypeTLog, "https://rekor.test", defaults))
+	assert.Nil(t, addService(&sc, TypeTLog, "https://rekor2.test;api=2", defaults))
+	assert.Equal(t, ptr.ServiceSelector_ALL, sc.GetRekorTlogConfig().GetSelector())
+
+	defaults.Start = "2025-01-01T00:00:00Z"
+	// Only the open service with the same API version is closed
+	assert.Nil(t, replaceService(&sc, TypeTLog, "https://rekor3.test", "", defaults, time.Time{}))
+	assert.Len(t, sc.RekorTlogUrls, 3)
+	// Services are ordered newest first
+	assert.Equal(t, "https://rekor3.test", sc.RekorTlogUrls[0].GetUrl())
+	assert.Equal(t, "2025-01-01T00:00:00Z", sc.RekorTlogUrls[2].GetValidFor().GetEnd().AsTime().Format(time.RFC3339))
+	assert.Nil(t, sc.RekorTlogUrls[1].GetValidFor().GetEnd())
+
+	end := time.Date(2025, 2, 1, 0, 0, 0, 0, time.UTC)
+	assert.Nil(t, replaceService(&sc, TypeTLog, "https://rekor4.test;api=2", "https://rekor2.test", defaults, end))
+	assert.Equal(t, end, sc.RekorTlogUrls[2].GetValidFor().GetEnd().AsTime())
+
+	assert.Nil(t, removeService(&sc, TypeTLog, "https://rekor.test"))
+	assert.Len(t, sc.RekorTlogUrls, 3)
+	assert.ErrorContains(t, removeService(&sc, TypeTLog, "https://rekor.test"), "no tlog service")
+	assert.ErrorContains(t, addService(&sc, "ctlog", "https://ct.test", defaults), "invalid service type")
+
+	// A replaced service without a start can not be given an end
+	sc.TsaUrls = []*ptr.Service{{Url: "https://tsa.test", MajorApiVersion: 1, Operator: "test.com"}}
+	assert.ErrorContains(t, replaceService(&sc, TypeTSA, "https://tsa2.test", "", defaults, time.Time{}),
+		"tsa service https://tsa.test has no validity start")
+	assert.Len(t, sc.TsaUrls, 1)
+}
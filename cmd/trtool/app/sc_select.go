@@ -0,0 +1,230 @@
+package app
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/peterbourgon/ff/v3/ffcli"
+	ptr "github.com/sigstore/protobuf-specs/gen/pb-go/trustroot/v1"
+)
+
+func SCSelect() *ffcli.Command {
+	var (
+		flagset  = flag.NewFlagSet("trtool sc-select", flag.ExitOnError)
+		file     = flagset.String("f", "", "Signing config to select services from")
+		at       = flagset.String("at", "", "Time to select services at, RFC 3339. Defaults to now")
+		versions = flagset.String("api-version", "1", "Comma separated API versions the client supports")
+		in       = addInputFlags(flagset)
+	)
+
+	return &ffcli.Command{
+		Name:       "sc-select",
+		ShortUsage: "trtool sc-select -f signing_config.json -at 2025-01-01T00:00:00Z -api-version 1,2",
+		ShortHelp:  "Show the services a client selects from a signing config",
+		LongHelp: `Show the CA, OIDC provider, transparency logs and TSAs a client selects
+from a signing config, or the signing config of a client trust config.
+A client only considers services valid at the given time with an API
+version it supports, and of those only the highest API version. It picks
+the service with the newest start for the CA and OIDC provider, and the
+one with the newest start per operator for the transparency logs and
+TSAs, which are then used as the selector says. Run it at the start and
+end of a validity period to see when clients switch services.`,
+		FlagSet: flagset,
+		Exec: func(ctx context.Context, args []string) error {
+			if *file == "" {
+				return flag.ErrHelp
+			}
+
+			var now = time.Now()
+			if *at != "" {
+				var err error
+				if now, err = time.Parse(time.RFC3339, *at); err != nil {
+					return fmt.Errorf("invalid time %s: %w", *at, err)
+				}
+			}
+			supported, err := parseAPIVersions(*versions)
+			if err != nil {
+				return err
+			}
+
+			b, err := os.ReadFile(*file)
+			if err != nil {
+				return err
+			}
+
+			return SCSelectCmd(os.Stdout, b, now, supported, *in)
+		},
+	}
+}
+
+func SCSelectCmd(w io.Writer, b []byte, now time.Time, supported []uint32, in InputOptions) error {
+	sc, err := unmarshalSigningConfig(b, in)
+	if err != nil {
+		return err
+	}
+
+	var failed bool
+	for _, t := range []struct {
+		name     string
+		services []*ptr.Service
+		required bool
+		config   *ptr.ServiceConfiguration
+	}{
+		{TypeCA, sc.GetCaUrls(), true, nil},
+		{TypeOIDC, sc.GetOidcUrls(), true, nil},
+		{TypeTLog, sc.GetRekorTlogUrls(), true, sc.GetRekorTlogConfig()},
+		{TypeTSA, sc.GetTsaUrls(), false, sc.GetTsaConfig()},
+	} {
+		var selected []*ptr.Service
+		var how string
+		if t.config == nil {
+			selected = selectServices(t.services, !isSigningConfigV01(sc), now, supported, false)
+			if len(selected) > 1 {
+				selected = selected[:1]
+			}
+		} else {
+			selected = selectServices(t.services, !isSigningConfigV01(sc), now, supported, true)
+			if how, err = applySelector(t.config, len(selected)); err != nil {
+				fmt.Fprintf(w, "%s: %s\n", t.name, err)
+				failed = true
+				continue
+			}
+		}
+
+		switch {
+		case len(selected) == 0 && t.required:
+			fmt.Fprintf(w, "%s: no service selected\n", t.name)
+			failed = true
+		case len(selected) == 0:
+			fmt.Fprintf(w, "%s: none\n", t.name)
+		case how == "":
+			fmt.Fprintf(w, "%s: %s\n", t.name, describeService(selected[0]))
+		default:
+			fmt.Fprintf(w, "%s: %s\n", t.name, how)
+			for _, s := range selected {
+				fmt.Fprintf(w, "  %s\n", describeService(s))
+			}
+		}
+	}
+	if failed {
+		return fmt.Errorf("no valid selection at %s for API versions %s",
+			now.UTC().Format(time.RFC3339), formatAPIVersions(supported))
+	}
+
+	return nil
+}
+
+// selectServices returns the services a client considers: valid at
+// now, of the highest supported API version, newest start first. With
+// perOperator only the newest service of each operator is kept.
+// Without windows all services are valid.
+func selectServices(services []*ptr.Service, windows bool, now time.Time, supported []uint32, perOperator bool) []*ptr.Service {
+	var candidates []*ptr.Service
+	var highest uint32
+	var found bool
+
+	for _, s := range services {
+		if windows && !covers(s.GetValidFor(), now) {
+			continue
+		}
+		if !slices.Contains(supported, s.GetMajorApiVersion()) {
+			continue
+		}
+		candidates = append(candidates, s)
+		if !found || s.GetMajorApiVersion() > highest {
+			highest = s.GetMajorApiVersion()
+			found = true
+		}
+	}
+
+	candidates = slices.DeleteFunc(candidates, func(s *ptr.Service) bool {
+		return s.GetMajorApiVersion() != highest
+	})
+	slices.SortStableFunc(candidates, func(a, b *ptr.Service) int {
+		return b.GetValidFor().GetStart().AsTime().Compare(a.GetValidFor().GetStart().AsTime())
+	})
+	if !perOperator {
+		return candidates
+	}
+
+	var selected []*ptr.Service
+	var seen = map[string]bool{}
+	for _, s := range candidates {
+		// Version 0.1 has no operators, there each service
+		// counts on its own.
+		if s.GetOperator() != "" && seen[s.GetOperator()] {
+			continue
+		}
+		seen[s.GetOperator()] = true
+		selected = append(selected, s)
+	}
+
+	return selected
+}
+
+// applySelector describes how a client uses the n selected services.
+func applySelector(c *ptr.ServiceConfiguration, n int) (string, error) {
+	switch c.GetSelector() {
+	case ptr.ServiceSelector_ALL:
+		return "all of", nil
+	case ptr.ServiceSelector_ANY:
+		return "any one of", nil
+	case ptr.ServiceSelector_EXACT:
+		if uint32(n) < c.GetCount() {
+			return "", fmt.Errorf("EXACT %d can not be satisfied by %d services", c.GetCount(), n)
+		}
+		return fmt.Sprintf("%d of", c.GetCount()), nil
+	}
+
+	return "", errors.New("no selector configured")
+}
+
+func describeService(s *ptr.Service) string {
+	var b strings.Builder
+
+	fmt.Fprintf(&b, "%s (API version %d", s.GetUrl(), s.GetMajorApiVersion())
+	if s.GetOperator() != "" {
+		fmt.Fprintf(&b, ", operator %s", s.GetOperator())
+	}
+	if start := s.GetValidFor().GetStart(); start != nil {
+		fmt.Fprintf(&b, ", valid from %s", start.AsTime().UTC().Format(time.RFC3339))
+	}
+	if end := s.GetValidFor().GetEnd(); end != nil {
+		fmt.Fprintf(&b, " to %s", end.AsTime().UTC().Format(time.RFC3339))
+	}
+	b.WriteString(")")
+
+	return b.String()
+}
+
+func parseAPIVersions(s string) ([]uint32, error) {
+	var versions []uint32
+
+	for _, v := range strings.Split(s, ",") {
+		n, err := strconv.ParseUint(strings.TrimSpace(v), 10, 32)
+		if err != nil {
+			return nil, fmt.Errorf("invalid API version %q: %w", v, err)
+		}
+		versions = append(versions, uint32(n))
+	}
+
+	return versions, nil
+}
+
+func formatAPIVersions(versions []uint32) string {
+	var s []string
+
+	for _, v := range versions {
+		s = append(s, strconv.FormatUint(uint64(v), 10))
+	}
+
+	return strings.Join(s, ",")
+}
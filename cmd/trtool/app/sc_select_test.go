@@ -0,0 +1,72 @@
+package app
+
+import (
+	"bytes"
+	"testing"
+	"time"
+
+	pc "github.com/sigstore/protobuf-specs/gen/pb-go/common/v1"
+	ptr "github.com/sigstore/protobuf-specs/gen/pb-go/trustroot/v1"
+	"github.com/stretchr/testify/assert"
+	"google.golang.org/protobuf/types/known/timestamppb"
+)
+
+func TestSelectServices(t *testing.T) {
+	var t0 = time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)
+	var t1 = t0.AddDate(0, 6, 0)
+	var window = func(start, end time.Time) *pc.TimeRange {
+		r := &pc.TimeRange{Start: timestamppb.New(start)}
+		if !end.IsZero() {
+			r.End = timestamppb.New(end)
+		}
+		return r
+	}
+	var services = []*ptr.Service{
+		{Url: "https://rekor.test", MajorApiVersion: 1, Operator: "a.test", ValidFor: window(t0, t1)},
+		{Url: "https://log2025.test", MajorApiVersion: 2, Operator: "a.test", ValidFor: window(t0, time.Time{})},
+		{Url: "https://log2026.test", MajorApiVersion: 2, Operator: "a.test", ValidFor: window(t1, time.Time{})},
+		{Url: "https://other.test", MajorApiVersion: 2, Operator: "b.test", ValidFor: window(t0, time.Time{})},
+	}
+	var urls = func(services []*ptr.Service) []string {
+		var s []string
+		for _, svc := range services {
+			s = append(s, svc.GetUrl())
+		}
+		return s
+	}
+
+	assert.Equal(t, []string{"https://rekor.test"},
+		urls(selectServices(services, true, t0, []uint32{1}, true)))
+	assert.Equal(t, []string{"https://log2025.test", "https://other.test"},
+		urls(selectServices(services, true, t0, []uint32{1, 2}, true)))
+	// The end is inclusive, and the newest shard per operator wins
+	assert.Equal(t, []string{"https://rekor.test"},
+		urls(selectServices(services, true, t1, []uint32{1}, true)))
+	assert.Equal(t, []string{"https://log2026.test", "https://other.test"},
+		urls(selectServices(services, true, t1, []uint32{2}, true)))
+	assert.Empty(t, selectServices(services, true, t1.Add(time.Second), []uint32{1}, true))
+
+	var sc = ptr.SigningConfig{
+		MediaType:       "application/vnd.dev.sigstore.signingconfig.v0.2+json",
+		CaUrls:          []*ptr.Service{{Url: "https://fulcio.test", MajorApiVersion: 1, ValidFor: window(t0, time.Time{})}},
+		OidcUrls:        []*ptr.Service{{Url: "https://oauth.test", MajorApiVersion: 1, ValidFor: window(t0, time.Time{})}},
+		RekorTlogUrls:   services,
+		RekorTlogConfig: &ptr.ServiceConfiguration{Selector: ptr.ServiceSelector_EXACT, Count: 2},
+	}
+	b, err := marshalCanonical(&sc, OutputOptions{Format: FormatJSON})
+	assert.Nil(t, err)
+
+	var w bytes.Buffer
+	assert.Nil(t, SCSelectCmd(&w, b, t1, []uint32{1, 2}, DefaultInputOptions))
+	assert.Equal(t, `ca: https://fulcio.test (API version 1, valid from 2025-01-01T00:00:00Z)
+oidc: https://oauth.test (API version 1, valid from 2025-01-01T00:00:00Z)
+tlog: 2 of
+  https://log2026.test (API version 2, operator a.test, valid from 2025-07-01T00:00:00Z)
+  https://other.test (API version 2, operator b.test, valid from 2025-01-01T00:00:00Z)
+tsa: none
+`, w.String())
+
+	w.Reset()
+	assert.ErrorContains(t, SCSelectCmd(&w, b, t1, []uint32{1}, DefaultInputOptions), "no valid selection")
+	assert.Contains(t, w.String(), "tlog: EXACT 2 can not be satisfied by 1 services\n")
+}
@@ -0,0 +1,254 @@
+package app
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"net/url"
+	"os"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/peterbourgon/ff/v3/ffcli"
+	pc "github.com/sigstore/protobuf-specs/gen/pb-go/common/v1"
+	ptr "github.com/sigstore/protobuf-specs/gen/pb-go/trustroot/v1"
+)
+
+func SCVerify() *ffcli.Command {
+	var (
+		flagset = flag.NewFlagSet("trtool sc-verify", flag.ExitOnError)
+		file    = flagset.String("f", "", "Signing config to verify")
+		verbose = flagset.Bool("v", false, "verbose mode")
+		in      = addInputFlags(flagset)
+	)
+
+	return &ffcli.Command{
+		Name:       "sc-verify",
+		ShortUsage: "trtool sc-verify -f signing_config.json",
+		ShortHelp:  "Verify signing config",
+		LongHelp: `Verify a signing config, or the signing config of a client trust
+config. Checks the media type, that all URLs are valid https URLs, that all
+services have an operator, that there are no duplicate services, that a CA,
+an OIDC provider and a transparency log are configured, that the validity
+periods are ordered newest first and cover the current time, and that the
+selectors can be satisfied by distinct operators.`,
+		FlagSet: flagset,
+		Exec: func(ctx context.Context, args []string) error {
+			if *file == "" {
+				return flag.ErrHelp
+			}
+
+			b, err := os.ReadFile(*file)
+			if err != nil {
+				return err
+			}
+
+			return SCVerifyCmd(b, *in, *verbose)
+		},
+	}
+}
+
+func SCVerifyCmd(b []byte, in InputOptions, verbose bool) error {
+	sc, err := unmarshalSigningConfig(b, in)
+	if err != nil {
+		return err
+	}
+
+	if !VerifySigningConfig(os.Stdout, sc, time.Now()) {
+		return errors.New("verification failed")
+	} else if verbose {
+		fmt.Println("Signing config is valid")
+	}
+
+	return nil
+}
+
+// VerifySigningConfig verifies the signing config at the given time,
+// and writes any findings to w.
+func VerifySigningConfig(w io.Writer, sc *ptr.SigningConfig, now time.Time) bool {
+	var findings = verifySigningConfig(sc, now)
+
+	for _, f := range findings {
+		fmt.Fprintln(w, f)
+	}
+
+	return len(findings) == 0
+}
+
+// scServiceType describes one type of service in a signing config.
+type scServiceType struct {
+	path     string
+	services []*ptr.Service
+	required bool
+	// configPath is set for types with a selector
+	configPath string
+	config     *ptr.ServiceConfiguration
+}
+
+func verifySigningConfig(sc *ptr.SigningConfig, now time.Time) []Finding {
+	var findings []Finding
+
+	if _, ok := versionOf(KindSigningConfig, sc.GetMediaType()); !ok {
+		findings = append(findings, Finding{"$.mediaType",
+			fmt.Sprintf("unknown media type %s, expected one of %s",
+				sc.GetMediaType(), strings.Join(mediaTypes(KindSigningConfig), ", "))})
+	}
+
+	types := []scServiceType{
+		{path: "$.caUrls", services: sc.GetCaUrls(), required: true},
+		{path: "$.oidcUrls", services: sc.GetOidcUrls(), required: true},
+		{path: "$.rekorTlogUrls", services: sc.GetRekorTlogUrls(), required: true,
+			configPath: "$.rekorTlogConfig", config: sc.GetRekorTlogConfig()},
+		{path: "$.tsaUrls", services: sc.GetTsaUrls(),
+			configPath: "$.tsaConfig", config: sc.GetTsaConfig()},
+	}
+	for _, t := range types {
+		findings = append(findings, verifyServices(t, !isSigningConfigV01(sc), now)...)
+	}
+
+	return findings
+}
+
+func verifyServices(t scServiceType, windows bool, now time.Time) []Finding {
+	var findings []Finding
+	var active uint32
+	// Clients select at most one service per operator, from the
+	// highest API version they support. Version 0.1 has no
+	// operators, there each service counts.
+	var operators = map[uint32][]string{}
+
+	add := func(p, format string, a ...any) {
+		findings = append(findings, Finding{p, fmt.Sprintf(format, a...)})
+	}
+
+	if t.required && len(t.services) == 0 {
+		add(t.path, "at least one service is required")
+	}
+
+	for i, s := range t.services {
+		p := fmt.Sprintf("%s[%d]", t.path, i)
+		if err := checkServiceURL(s.GetUrl()); err != nil {
+			add(p+".url", "%s", err)
+		}
+		for j, prev := range t.services[:i] {
+			if prev.GetUrl() == s.GetUrl() &&
+				prev.GetMajorApiVersion() == s.GetMajorApiVersion() &&
+				overlaps(prev, s) {
+				add(p, "duplicate of %s[%d]", t.path, j)
+				break
+			}
+		}
+
+		if !windows {
+			active++
+			operators[s.GetMajorApiVersion()] = append(operators[s.GetMajorApiVersion()], s.GetUrl())
+			continue
+		}
+		start := s.GetValidFor().GetStart()
+		if start == nil {
+			add(p+".validFor.start", "required field is missing")
+			continue
+		}
+		end := s.GetValidFor().GetEnd()
+		if end != nil && !end.AsTime().After(start.AsTime()) {
+			add(p+".validFor", "ends at %s, before it starts",
+				end.AsTime().Format(time.RFC3339))
+		}
+		if i > 0 {
+			prevStart := t.services[i-1].GetValidFor().GetStart()
+			if prevStart != nil && start.AsTime().After(prevStart.AsTime()) {
+				add(p+".validFor.start", "%s is after the previous service's start %s, services must be ordered from newest to oldest",
+					start.AsTime().Format(time.RFC3339), prevStart.AsTime().Format(time.RFC3339))
+			}
+		}
+		if s.GetOperator() == "" {
+			add(p+".operator", "required field is missing")
+		}
+		if covers(s.GetValidFor(), now) {
+			active++
+			operators[s.GetMajorApiVersion()] = append(operators[s.GetMajorApiVersion()], s.GetOperator())
+		}
+	}
+	if len(t.services) > 0 && active == 0 {
+		add(t.path, "no service is valid at %s", now.UTC().Format(time.RFC3339))
+	}
+
+	if t.configPath == "" {
+		return findings
+	}
+	cp, c := t.configPath, t.config
+	switch {
+	case c == nil && len(t.services) > 0:
+		add(cp, "required when services are configured")
+	case c == nil:
+	case c.GetSelector() == ptr.ServiceSelector_SERVICE_SELECTOR_UNDEFINED:
+		add(cp+".selector", "required field is missing")
+	case c.GetSelector() == ptr.ServiceSelector_EXACT && c.GetCount() == 0:
+		add(cp+".count", "EXACT requires a count")
+	case c.GetSelector() == ptr.ServiceSelector_EXACT:
+		var versions []uint32
+		for v := range operators {
+			versions = append(versions, v)
+		}
+		slices.Sort(versions)
+		for _, v := range versions {
+			if n := len(slices.Compact(slices.Sorted(slices.Values(operators[v])))); uint32(n) < c.GetCount() {
+				add(cp+".count", "EXACT %d can not be satisfied by the %d distinct operators of valid API version %d services",
+					c.GetCount(), n, v)
+			}
+		}
+	case c.GetCount() != 0:
+		add(cp+".count", "only used with EXACT")
+	}
+
+	return findings
+}
+
+// checkServiceURL requires an absolute https URL.
+func checkServiceURL(s string) error {
+	if s == "" {
+		return errors.New("empty URL")
+	}
+	u, err := url.Parse(s)
+	if err != nil {
+		return fmt.Errorf("invalid URL: %w", err)
+	}
+	if u.Scheme != "https" {
+		return fmt.Errorf("%s does not use https", s)
+	}
+	if u.Host == "" {
+		return fmt.Errorf("%s has no host", s)
+	}
+
+	return nil
+}
+
+// overlaps tells if the validity periods of two services overlap. A
+// missing start or end is open.
+func overlaps(a, b *ptr.Service) bool {
+	aStart, aEnd := a.GetValidFor().GetStart(), a.GetValidFor().GetEnd()
+	bStart, bEnd := b.GetValidFor().GetStart(), b.GetValidFor().GetEnd()
+
+	if aEnd != nil && bStart != nil && !aEnd.AsTime().After(bStart.AsTime()) {
+		return false
+	}
+	if bEnd != nil && aStart != nil && !bEnd.AsTime().After(aStart.AsTime()) {
+		return false
+	}
+
+	return true
+}
+
+// covers tells if t is within the time range, inclusive of both ends.
+// A range without a start covers nothing, a range without an end is
+// open.
+func covers(r *pc.TimeRange, t time.Time) bool {
+	if r.GetStart() == nil || r.GetStart().AsTime().After(t) {
+		return false
+	}
+
+	return r.GetEnd() == nil || !t.After(r.GetEnd().AsTime())
+}
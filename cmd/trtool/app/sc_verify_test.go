@@ -0,0 +1,51 @@
+package app
+
+import (
+	"testing"
+	"time"
+
+	ptr "github.com/sigstore/protobuf-specs/gen/pb-go/trustroot/v1"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestVerifySigningConfig(t *testing.T) {
+	var sc ptr.SigningConfig
+	var now = time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)
+	var doc = []byte(`{
+  "mediaType": "application/vnd.dev.sigstore.signingconfig.v0.2+json",
+  "caUrls": [
+    {"url": "http://ca.test", "majorApiVersion": 1, "validFor": {"start": "2024-01-01T00:00:00Z"}, "operator": "a.test"}
+  ],
+  "oidcUrls": [
+    {"url": "https://oauth2.test", "majorApiVersion": 1, "validFor": {"start": "2024-01-01T00:00:00Z"}, "operator": "a.test"}
+  ],
+  "rekorTlogUrls": [
+    {"url": "https://rekor.test", "majorApiVersion": 1, "validFor": {"start": "2024-06-01T00:00:00Z"}, "operator": "a.test"},
+    {"url": "https://rekor.test", "majorApiVersion": 1, "validFor": {"start": "2024-01-01T00:00:00Z"}, "operator": "a.test"},
+    {"url": "https://rekor2.test", "majorApiVersion": 2, "validFor": {"start": "2024-03-01T00:00:00Z"}, "operator": "b.test"},
+    {"url": "https://rekor3.test", "majorApiVersion": 2, "validFor": {"start": "2024-02-01T00:00:00Z", "end": "2025-01-01T00:00:00Z"}, "operator": "c.test"}
+  ],
+  "rekorTlogConfig": {"selector": "EXACT", "count": 2},
+  "tsaUrls": [
+    {"url": "", "majorApiVersion": 1, "validFor": {"start": "2026-01-01T00:00:00Z"}}
+  ],
+  "tsaConfig": {"selector": "ANY"}
+}`)
+
+	assert.Nil(t, unmarshalProto(doc, &sc, DefaultInputOptions))
+	assert.Equal(t, []Finding{
+		{"$.caUrls[0].url", "http://ca.test does not use https"},
+		{"$.rekorTlogUrls[1]", "duplicate of $.rekorTlogUrls[0]"},
+		{"$.rekorTlogUrls[2].validFor.start", "2024-03-01T00:00:00Z is after the previous service's start 2024-01-01T00:00:00Z, services must be ordered from newest to oldest"},
+		{"$.rekorTlogConfig.count", "EXACT 2 can not be satisfied by the 1 distinct operators of valid API version 1 services"},
+		{"$.tsaUrls[0].url", "empty URL"},
+		{"$.tsaUrls[0].operator", "required field is missing"},
+		{"$.tsaUrls", "no service is valid at 2025-01-01T00:00:00Z"},
+	}, verifySigningConfig(&sc, now))
+
+	sc.CaUrls[0].Url = "https://ca.test"
+	sc.RekorTlogUrls = sc.RekorTlogUrls[:1]
+	sc.RekorTlogConfig = &ptr.ServiceConfiguration{Selector: ptr.ServiceSelector_ANY}
+	sc.TsaUrls, sc.TsaConfig = nil, nil
+	assert.Empty(t, verifySigningConfig(&sc, now))
+}
@@ -0,0 +1,245 @@
+package app
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"path/filepath"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/peterbourgon/ff/v3/ffcli"
+	ptr "github.com/sigstore/protobuf-specs/gen/pb-go/trustroot/v1"
+)
+
+const (
+	IntervalYearly     = "yearly"
+	IntervalHalfYearly = "half-yearly"
+	IntervalQuarterly  = "quarterly"
+)
+
+var shardIntervals = map[string]int{
+	IntervalYearly:     12,
+	IntervalHalfYearly: 6,
+	IntervalQuarterly:  3,
+}
+
+func AddShards() *ffcli.Command {
+	var (
+		flagset  = flag.NewFlagSet("trtool add-shards", flag.ExitOnError)
+		tr       = flagset.String("f", "trusted_root.json", "Trusted root file to update")
+		nType    = flagset.String("type", "", "the type, tlog or ctlog")
+		template = flagset.String("uri-template", "", "URI of the shards, with {year}, {half} and {quarter} placeholders")
+		pemDir   = flagset.String("pem-dir", "", "Directory with a PEM key per shard")
+		interval = flagset.String("interval", IntervalYearly, "Shard interval, yearly, half-yearly or quarterly")
+		start    = flagset.String("start", "", "Validity start of the first shard")
+		openLast = flagset.Bool("open-last", false, "Leave the validity of the last shard open")
+		padding  = flagset.String("padding", "pkcs1v15", "For RSA key, the padding scheme to use. PKCS#1 v1.5 is the default, pss is also supported")
+		verbose  = flagset.Bool("v", false, "verbose mode")
+		in       = addInputFlags(flagset)
+		out      = addOutputFlags(flagset)
+	)
+
+	return &ffcli.Command{
+		Name:       "add-shards",
+		ShortUsage: "trtool add-shards -type ctlog -uri-template https://ct.example/{year} -pem-dir keys/ -start 2025-01-01T00:00:00Z",
+		ShortHelp:  "Add a series of log shards",
+		LongHelp: `Add a log shard for each *.pem key in -pem-dir, in file name order, with
+consecutive validity periods of one interval from -start. The URI of each
+shard is the template with {year}, {half} (1 or 2) and {quarter} (1 to 4)
+of its start. The last shard ends after its interval unless -open-last is
+set. Existing entries are not closed, but all logs with URIs matching the
+template are a family of shards, which must tile the timeline.`,
+		FlagSet: flagset,
+		Exec: func(ctx context.Context, args []string) error {
+			if *nType != TypeTLog && *nType != TypeCTLog {
+				return flag.ErrHelp
+			}
+			if *template == "" {
+				return fmt.Errorf("no uri template provided: %w", flag.ErrHelp)
+			}
+			if *pemDir == "" {
+				return fmt.Errorf("no pem directory provided: %w", flag.ErrHelp)
+			}
+			if *start == "" {
+				return fmt.Errorf("no start provided: %w", flag.ErrHelp)
+			}
+			if _, ok := shardIntervals[*interval]; !ok {
+				return fmt.Errorf("invalid interval %q: %w", *interval, flag.ErrHelp)
+			}
+			if *padding != RSAPKCS1v15 && *padding != RSAPSS {
+				return fmt.Errorf("invalid RSA padding: %w", flag.ErrHelp)
+			}
+
+			return AddShardsCmd(*tr, *nType, *template, *pemDir, *interval, *start, *padding, *openLast, *verbose, *in, *out)
+		},
+	}
+}
+
+func AddShardsCmd(trp, nType, template, pemDir, interval, start, padding string, openLast, verbose bool, in InputOptions, out OutputOptions) error {
+	startTs, err := time.Parse(time.RFC3339, start)
+	if err != nil {
+		return fmt.Errorf("invalid start %s: %w", start, err)
+	}
+	tr, err := readTrustedRoot(trp, in)
+	if err != nil {
+		return err
+	}
+	keys, err := filepath.Glob(filepath.Join(pemDir, "*.pem"))
+	if err != nil {
+		return err
+	}
+	if len(keys) == 0 {
+		return fmt.Errorf("no *.pem files in %s", pemDir)
+	}
+
+	shards, err := newShards(keys, template, shardIntervals[interval], startTs, padding, openLast, verbose)
+	if err != nil {
+		return err
+	}
+	if err = addShards(tr, nType, template, shards); err != nil {
+		return err
+	}
+
+	return printProto(tr, out)
+}
+
+// addShards appends the shards and requires the family of the template
+// to tile the timeline.
+func addShards(tr *ptr.TrustedRoot, nType, template string, shards []*ptr.TransparencyLogInstance) error {
+	var tlogs = &tr.Tlogs
+	var field = "tlogs"
+	if nType == TypeCTLog {
+		tlogs = &tr.Ctlogs
+		field = "ctlogs"
+	}
+	*tlogs = append(*tlogs, shards...)
+
+	if findings := checkShards(field, *tlogs, templateFamily(template)); len(findings) > 0 {
+		var errs []error
+		for _, f := range findings {
+			errs = append(errs, errors.New(f.String()))
+		}
+		return fmt.Errorf("the shards do not tile the timeline:\n%w", errors.Join(errs...))
+	}
+
+	return nil
+}
+
+// newShards creates a shard per key, each valid for months from the
+// end of the previous one. All periods are computed from start, so
+// each shard ends exactly when the next one starts.
+func newShards(keys []string, template string, months int, start time.Time, padding string,
+	openLast, verbose bool) ([]*ptr.TransparencyLogInstance, error) {
+	var shards []*ptr.TransparencyLogInstance
+
+	for i, k := range keys {
+		s := start.AddDate(0, i*months, 0)
+		e := start.AddDate(0, (i+1)*months, 0).Format(time.RFC3339)
+		if openLast && i == len(keys)-1 {
+			e = ""
+		}
+		tl, err := newTLog(k, s.Format(time.RFC3339), e, shardURI(template, s), padding, verbose)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", k, err)
+		}
+		shards = append(shards, tl)
+	}
+
+	return shards, nil
+}
+
+func shardURI(template string, t time.Time) string {
+	return strings.NewReplacer(
+		"{year}", strconv.Itoa(t.Year()),
+		"{half}", strconv.Itoa((int(t.Month())-1)/6+1),
+		"{quarter}", strconv.Itoa((int(t.Month())-1)/3+1),
+	).Replace(template)
+}
+
+// templateFamily returns a family of the logs with URIs matching the
+// template, with any year, half and quarter.
+func templateFamily(template string) func(string) (string, bool) {
+	pattern := strings.NewReplacer(
+		`\{year\}`, "[0-9]{4}",
+		`\{half\}`, "[12]",
+		`\{quarter\}`, "[1-4]",
+	).Replace(regexp.QuoteMeta(strings.TrimSuffix(template, "/")))
+	re := regexp.MustCompile("^" + pattern + "$")
+
+	return func(uri string) (string, bool) {
+		return template, re.MatchString(strings.TrimSuffix(uri, "/"))
+	}
+}
+
+// shardPeriod is a year, optionally followed by a separator, h or q
+// and a half or quarter, as the add-shards placeholders write them.
+// Only the character after the period is kept.
+var shardPeriod = regexp.MustCompile("(19|20)[0-9]{2}([-_./]?[hq]?[1-4]([^0-9]|$))?")
+
+// shardFamily returns the URI with the shard period replaced. This is
+// a guess, the trusted root does not record the template, so findings
+// from it are warnings. URIs without a period are not shards.
+func shardFamily(uri string) (string, bool) {
+	uri = strings.TrimSuffix(uri, "/")
+	if !shardPeriod.MatchString(uri) {
+		return "", false
+	}
+	return shardPeriod.ReplaceAllString(uri, "#${3}"), true
+}
+
+// checkShards requires the shards of each family to have consecutive
+// validity periods. Logs that share a URI but no family are key
+// rotations, not shards.
+func checkShards(field string, tlogs []*ptr.TransparencyLogInstance, family func(string) (string, bool)) []Finding {
+	var findings []Finding
+	var families = map[string][]int{}
+	var order []string
+
+	for i, tl := range tlogs {
+		f, ok := family(tl.GetBaseUrl())
+		if !ok {
+			continue
+		}
+		if _, ok := families[f]; !ok {
+			order = append(order, f)
+		}
+		families[f] = append(families[f], i)
+	}
+
+	for _, f := range order {
+		idx := families[f]
+		if !slices.ContainsFunc(idx, func(i int) bool {
+			return tlogs[i].GetBaseUrl() != tlogs[idx[0]].GetBaseUrl()
+		}) {
+			continue
+		}
+		slices.SortStableFunc(idx, func(a, b int) int {
+			return tlogs[a].GetPublicKey().GetValidFor().GetStart().AsTime().Compare(
+				tlogs[b].GetPublicKey().GetValidFor().GetStart().AsTime())
+		})
+		for j := 1; j < len(idx); j++ {
+			prev := tlogs[idx[j-1]]
+			next := tlogs[idx[j]].GetPublicKey().GetValidFor().GetStart().AsTime()
+			path := fmt.Sprintf("$.%s[%d].publicKey.validFor", field, idx[j-1])
+			end := prev.GetPublicKey().GetValidFor().GetEnd()
+			switch {
+			case end == nil:
+				findings = append(findings, Finding{path, fmt.Sprintf("%s is open, but shard %s starts at %s",
+					prev.GetBaseUrl(), tlogs[idx[j]].GetBaseUrl(), next.Format(time.RFC3339))})
+			case end.AsTime().Before(next):
+				findings = append(findings, Finding{path, fmt.Sprintf("gap between %s ending at %s and %s",
+					prev.GetBaseUrl(), end.AsTime().Format(time.RFC3339), tlogs[idx[j]].GetBaseUrl())})
+			case end.AsTime().After(next):
+				findings = append(findings, Finding{path, fmt.Sprintf("%s ends at %s, after shard %s starts",
+					prev.GetBaseUrl(), end.AsTime().Format(time.RFC3339), tlogs[idx[j]].GetBaseUrl())})
+			}
+		}
+	}
+
+	return findings
+}
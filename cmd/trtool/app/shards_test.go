@@ -0,0 +1,120 @@
+package app
+
+import (
+	"bytes"
+	"path/filepath"
+	"testing"
+	"time"
+
+	pc "github.com/sigstore/protobuf-specs/gen/pb-go/common/v1"
+	ptr "github.com/sigstore/protobuf-specs/gen/pb-go/trustroot/v1"
+	"github.com/stretchr/testify/assert"
+	"google.golang.org/protobuf/types/known/timestamppb"
+)
+
+func TestShardURI(t *testing.T) {
+	ts := time.Date(2025, 7, 1, 0, 0, 0, 0, time.UTC)
+
+	assert.Equal(t, "https://ct.test/2025h2", shardURI("https://ct.test/{year}h{half}", ts))
+	assert.Equal(t, "https://ct.test/2025q3", shardURI("https://ct.test/{year}q{quarter}", ts))
+}
+
+func TestCheckShards(t *testing.T) {
+	shard := func(uri, start, end string) *ptr.TransparencyLogInstance {
+		s, _ := time.Parse(time.RFC3339, start)
+		vf := &pc.TimeRange{Start: timestamppb.New(s)}
+		if end != "" {
+			e, _ := time.Parse(time.RFC3339, end)
+			vf.End = timestamppb.New(e)
+		}
+		return &ptr.TransparencyLogInstance{BaseUrl: uri, PublicKey: &pc.PublicKey{ValidFor: vf}}
+	}
+
+	tlogs := []*ptr.TransparencyLogInstance{
+		shard("https://ct.test/2025", "2025-01-01T00:00:00Z", "2026-01-01T00:00:00Z"),
+		shard("https://ct.test/2024", "2024-01-01T00:00:00Z", "2025-01-01T00:00:00Z"),
+		shard("https://ct.test/2026", "2026-01-01T00:00:00Z", ""),
+		// Key rotations of a single log are not shards
+		shard("https://rekor.test", "2024-01-01T00:00:00Z", "2025-06-01T00:00:00Z"),
+		shard("https://rekor.test", "2025-01-01T00:00:00Z", ""),
+		// Numbered logs without a period are not shards
+		shard("https://ct1.test", "2024-01-01T00:00:00Z", ""),
+		shard("https://ct2.test", "2025-01-01T00:00:00Z", ""),
+	}
+	assert.Empty(t, checkShards("ctlogs", tlogs, shardFamily))
+	assert.Empty(t, checkShards("ctlogs", tlogs, templateFamily("https://ct.test/{year}")))
+	assert.Empty(t, checkShards("ctlogs", tlogs, templateFamily("https://ct{year}.test")))
+
+	var w bytes.Buffer
+	assert.True(t, VerifyTrustedRoot(&w, &ptr.TrustedRoot{Ctlogs: tlogs}, false))
+
+	tlogs[1].PublicKey.ValidFor.End = timestamppb.New(time.Date(2024, 12, 1, 0, 0, 0, 0, time.UTC))
+	tlogs = append(tlogs, shard("https://ct.test/2027", "2027-01-01T00:00:00Z", ""))
+	findings := checkShards("ctlogs", tlogs, templateFamily("https://ct.test/{year}"))
+	assert.Len(t, findings, 2)
+	assert.Equal(t, "$.ctlogs[1].publicKey.validFor", findings[0].Path)
+	assert.Contains(t, findings[0].Message, "gap")
+	assert.Contains(t, findings[1].Message, "is open")
+	assert.Equal(t, findings, checkShards("ctlogs", tlogs, shardFamily))
+
+	// verify guesses the families, so it only warns
+	w.Reset()
+	assert.True(t, VerifyTrustedRoot(&w, &ptr.TrustedRoot{Ctlogs: tlogs}, false))
+	assert.Contains(t, w.String(), "WARNING: $.ctlogs[2].publicKey.validFor")
+}
+
+func TestShardFamily(t *testing.T) {
+	f, ok := shardFamily("https://ct.test/2025-1/")
+	assert.True(t, ok)
+	assert.Equal(t, "https://ct.test/#", f)
+	for _, uri := range []string{"https://ct.test/2025-2", "https://ct.test/2026-1"} {
+		g, _ := shardFamily(uri)
+		assert.Equal(t, f, g)
+	}
+	f, _ = shardFamily("https://ct.test/2025/3/log")
+	assert.Equal(t, "https://ct.test/#/log", f)
+	f, _ = shardFamily("https://argon2025h1.ct.test")
+	assert.Equal(t, "https://argon#.ct.test", f)
+	_, ok = shardFamily("https://ct1.test")
+	assert.False(t, ok)
+}
+
+func TestAddShardsVerify(t *testing.T) {
+	key := filepath.Join(testData, "rekor.pkix.pem")
+	keys := []string{key, key, key, key}
+	start := time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)
+
+	for template, interval := range map[string]string{
+		"https://ct.test/{year}":            IntervalYearly,
+		"https://ct.test/{year}h{half}":     IntervalHalfYearly,
+		"https://ct.test/{year}-{half}":     IntervalHalfYearly,
+		"https://ct{year}-{half}.test":      IntervalHalfYearly,
+		"https://ct.test/{year}q{quarter}":  IntervalQuarterly,
+		"https://ct.test/{year}/{quarter}/": IntervalQuarterly,
+	} {
+		var tr ptr.TrustedRoot
+		shards, err := newShards(keys, template, shardIntervals[interval], start, RSAPKCS1v15, true, false)
+		assert.NoError(t, err)
+		assert.NoError(t, addShards(&tr, TypeCTLog, template, shards), template)
+
+		var w bytes.Buffer
+		assert.True(t, VerifyTrustedRoot(&w, &tr, false), template)
+		assert.Empty(t, w.String(), template)
+	}
+}
+
+func TestNewShards(t *testing.T) {
+	key := filepath.Join(testData, "rekor.pkix.pem")
+	start := time.Date(2025, 1, 31, 0, 0, 0, 0, time.UTC)
+
+	shards, err := newShards([]string{key, key, key}, "https://ct.test/{year}q{quarter}",
+		3, start, RSAPKCS1v15, false, false)
+	assert.NoError(t, err)
+	assert.Len(t, shards, 3)
+	for i := 1; i < len(shards); i++ {
+		assert.Equal(t, shards[i-1].PublicKey.ValidFor.End.AsTime(), shards[i].PublicKey.ValidFor.Start.AsTime())
+	}
+	assert.Equal(t, time.Date(2025, 7, 31, 0, 0, 0, 0, time.UTC), shards[2].PublicKey.ValidFor.Start.AsTime())
+	assert.Equal(t, time.Date(2025, 10, 31, 0, 0, 0, 0, time.UTC), shards[2].PublicKey.ValidFor.End.AsTime())
+	assert.Empty(t, checkShards("ctlogs", shards, templateFamily("https://ct.test/{year}q{quarter}")))
+}
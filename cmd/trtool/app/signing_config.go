@@ -4,62 +4,339 @@ import (
 	"context"
 	"flag"
 	"fmt"
+	"slices"
+	"strconv"
 	"strings"
+	"time"
 
 	"github.com/peterbourgon/ff/v3/ffcli"
+	pc "github.com/sigstore/protobuf-specs/gen/pb-go/common/v1"
 	ptr "github.com/sigstore/protobuf-specs/gen/pb-go/trustroot/v1"
-	"google.golang.org/protobuf/encoding/protojson"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/types/known/timestamppb"
 )
 
+// serviceHelp describes the service syntax shared by the signing
+// config commands.
+const serviceHelp = `A service is a URL optionally followed by ; separated attributes:
+  api=N          major API version, defaults to 1
+  operator=NAME  operator of the service, required for version 0.2
+  start=TIME     start of the validity period, RFC 3339
+  end=TIME       end of the validity period, RFC 3339
+For example -tlog 'https://rekor.example;api=2;operator=example.com'.
+Service flags can be repeated or hold a comma separated list.`
+
+// serviceList is a repeatable flag of comma separated services.
+type serviceList []string
+
+func (l *serviceList) String() string {
+	return strings.Join(*l, ",")
+}
+
+func (l *serviceList) Set(s string) error {
+	*l = append(*l, strings.Split(s, ",")...)
+	return nil
+}
+
 func SCInit() *ffcli.Command {
 	var (
-		flagset = flag.NewFlagSet("trtool sc-init", flag.ExitOnError)
-		ca      = flagset.String("ca", "", "URL to the ca")
-		oidc    = flagset.String("op", "", "URL to the OIDC Provider")
-		tlog    = flagset.String("tlog", "", "Comma separated list of transparency log URLs")
-		tsa     = flagset.String("tsa", "", "Comma separated list of timetsamp authorities URLs")
+		flagset   = flag.NewFlagSet("trtool sc-init", flag.ExitOnError)
+		cas       serviceList
+		oidcs     serviceList
+		tlogs     serviceList
+		tsas      serviceList
+		tlogSel   = flagset.String("tlog-selector", "ALL", "Transparency logs to use, ALL, ANY or EXACT")
+		tlogCount = flagset.Uint("tlog-count", 0, "Number of transparency logs to use with -tlog-selector EXACT")
+		tsaSel    = flagset.String("tsa-selector", "ALL", "Timestamp authorities to use, ALL, ANY or EXACT")
+		tsaCount  = flagset.Uint("tsa-count", 0, "Number of timestamp authorities to use with -tsa-selector EXACT")
+		version   = flagset.String("version", DefaultSigningConfigVersion, "Signing config version to create, 0.1 or 0.2")
+		defaults  = addServiceFlags(flagset)
+		fromRoot  = flagset.String("from-root", "", "Trusted root to take the CA, transparency log and TSA services from")
+		in        = addInputFlags(flagset)
+		out       = addOutputFlags(flagset)
 	)
+	flagset.Var(&cas, "ca", "CA service")
+	flagset.Var(&oidcs, "op", "OIDC provider service")
+	flagset.Var(&tlogs, "tlog", "Transparency log service")
+	flagset.Var(&tsas, "tsa", "Timestamp authority service")
 
 	return &ffcli.Command{
 		Name:       "sc-init",
-		ShortUsage: "trtool sc-init -ca https://test.com -tlog https://example.com,https://test.com",
+		ShortUsage: "trtool sc-init -ca https://test.com -tlog https://example.com,https://test.com -operator test.com",
 		ShortHelp:  "Initialize a signing config",
-		LongHelp:   "Initialize a signing config",
-		FlagSet:    flagset,
+		LongHelp: `Initialize a signing config.
+` + serviceHelp + `
+A version 0.2 signing config is created unless -version is given, and
+every service needs an operator, use -version 0.1 for the old format.
+Version 0.1 only supports a single CA and OIDC provider, API version 1
+and no operators, validity periods or selectors other than ALL.
+With -from-root the CAs, transparency logs and TSAs valid now in the trusted
+root are added, with their URL, operator and validity period, leaving only
+the OIDC provider to be supplied. The trusted root does not record API
+versions, so these services get API version 1. Services given with -ca,
+-tlog or -tsa replace derived services with the same URL.`,
+		FlagSet: flagset,
 		Exec: func(ctx context.Context, args []string) error {
-			var tlogs []string
-			var tsas []string
+			return SCInitCmd(*version, *fromRoot, cas, oidcs, tlogs, tsas,
+				*tlogSel, *tlogCount, *tsaSel, *tsaCount,
+				*defaults, *in, *out)
+		},
+	}
+}
 
-			if *tlog != "" {
-				tlogs = strings.Split(*tlog, ",")
-			}
+func SCInitCmd(version, root string, cas, oidcs, tlogs, tsas []string,
+	tlogSel string, tlogCount uint, tsaSel string, tsaCount uint,
+	defaults ServiceDefaults, in InputOptions, out OutputOptions) error {
+	v, err := lookupVersion(KindSigningConfig, version)
+	if err != nil {
+		return err
+	}
 
-			if *tsa != "" {
-				tsas = strings.Split(*tsa, ",")
+	var sc = ptr.SigningConfig{
+		MediaType: v.MediaType,
+	}
+	if sc.CaUrls, err = parseServices(cas); err != nil {
+		return fmt.Errorf("invalid CA: %w", err)
+	}
+	if sc.OidcUrls, err = parseServices(oidcs); err != nil {
+		return fmt.Errorf("invalid OIDC provider: %w", err)
+	}
+	if sc.RekorTlogUrls, err = parseServices(tlogs); err != nil {
+		return fmt.Errorf("invalid transparency log: %w", err)
+	}
+	if sc.TsaUrls, err = parseServices(tsas); err != nil {
+		return fmt.Errorf("invalid timestamp authority: %w", err)
+	}
+	if root != "" {
+		tr, err := readTrustedRoot(root, in)
+		if err != nil {
+			return err
+		}
+		if err = addRootServices(&sc, tr, time.Now()); err != nil {
+			return err
+		}
+	}
+	if len(sc.RekorTlogUrls) > 0 {
+		if sc.RekorTlogConfig, err = newServiceConfig(tlogSel, tlogCount); err != nil {
+			return fmt.Errorf("invalid transparency log selector: %w", err)
+		}
+	}
+	if len(sc.TsaUrls) > 0 {
+		if sc.TsaConfig, err = newServiceConfig(tsaSel, tsaCount); err != nil {
+			return fmt.Errorf("invalid timestamp authority selector: %w", err)
+		}
+	}
+
+	if !isSigningConfigV01(&sc) {
+		if err = defaults.applyAll(&sc); err != nil {
+			return err
+		}
+	}
+
+	return printProto(&sc, out)
+}
+
+// addRootServices adds the CAs, transparency logs and TSAs valid at
+// now in the trusted root after the services already configured,
+// newest first.
+// Services with the URL of an already configured service are skipped,
+// as are later entries with the same URL, e.g. during a key rotation.
+func addRootServices(sc *ptr.SigningConfig, tr *ptr.TrustedRoot, now time.Time) error {
+	var windows = !isSigningConfigV01(sc)
+
+	derive := func(svcs *[]*ptr.Service, path, url, operator string, validFor *pc.TimeRange, derived *[]*ptr.Service) error {
+		if !covers(validFor, now) {
+			return nil
+		}
+		if url == "" {
+			return fmt.Errorf("%s has no URL", path)
+		}
+		for _, s := range append(*svcs, *derived...) {
+			if s.GetUrl() == url {
+				return nil
 			}
+		}
+		s := &ptr.Service{Url: url, MajorApiVersion: 1, Operator: operator}
+		if windows {
+			s.ValidFor = proto.Clone(validFor).(*pc.TimeRange)
+		}
+		*derived = append(*derived, s)
+		return nil
+	}
 
-			return SCInitCmd(*ca, *oidc, tlogs, tsas)
-		},
+	var cas, tlogs, tsas []*ptr.Service
+	for i, ca := range tr.GetCertificateAuthorities() {
+		p := fmt.Sprintf("certificateAuthorities[%d]", i)
+		if err := derive(&sc.CaUrls, p, ca.GetUri(), ca.GetOperator(), ca.GetValidFor(), &cas); err != nil {
+			return err
+		}
+	}
+	for i, tl := range tr.GetTlogs() {
+		p := fmt.Sprintf("tlogs[%d]", i)
+		if err := derive(&sc.RekorTlogUrls, p, tl.GetBaseUrl(), tl.GetOperator(), tl.GetPublicKey().GetValidFor(), &tlogs); err != nil {
+			return err
+		}
 	}
+	for i, ca := range tr.GetTimestampAuthorities() {
+		p := fmt.Sprintf("timestampAuthorities[%d]", i)
+		if err := derive(&sc.TsaUrls, p, ca.GetUri(), ca.GetOperator(), ca.GetValidFor(), &tsas); err != nil {
+			return err
+		}
+	}
+
+	// Services are ordered newest first, the trusted root entries
+	// usually oldest first
+	for _, svcs := range [][]*ptr.Service{cas, tlogs, tsas} {
+		slices.SortStableFunc(svcs, func(a, b *ptr.Service) int {
+			return b.GetValidFor().GetStart().AsTime().Compare(a.GetValidFor().GetStart().AsTime())
+		})
+	}
+	sc.CaUrls = append(sc.CaUrls, cas...)
+	sc.RekorTlogUrls = append(sc.RekorTlogUrls, tlogs...)
+	sc.TsaUrls = append(sc.TsaUrls, tsas...)
+
+	return nil
 }
 
-func SCInitCmd(ca, oidc string, tlogs, tsas []string) error {
-	const mediaType = "application/vnd.dev.sigstore.signingconfig.v0.1+json"
-	var buf []byte
-	var sc = ptr.SigningConfig{
-		MediaType: mediaType,
-		CaUrl: ca,
-		OidcUrl: oidc,
-		TlogUrls: tlogs,
-		TsaUrls: tsas,
+func parseServices(specs []string) ([]*ptr.Service, error) {
+	var svcs []*ptr.Service
+
+	for _, spec := range specs {
+		s, err := parseService(spec)
+		if err != nil {
+			return nil, err
+		}
+		svcs = append(svcs, s)
 	}
-	var err error
 
-	if buf, err = protojson.Marshal(&sc); err != nil {
-		return err
+	return svcs, nil
+}
+
+// parseService parses a URL followed by ; separated attributes, see
+// serviceHelp.
+func parseService(spec string) (*ptr.Service, error) {
+	var parts = strings.Split(spec, ";")
+	var s = ptr.Service{
+		Url:             strings.TrimSpace(parts[0]),
+		MajorApiVersion: 1,
+	}
+
+	if s.Url == "" {
+		return nil, fmt.Errorf("empty URL in %q", spec)
+	}
+	for _, attr := range parts[1:] {
+		k, v, ok := strings.Cut(attr, "=")
+		if !ok {
+			return nil, fmt.Errorf("%s: expected key=value, got %q", s.Url, attr)
+		}
+		k, v = strings.TrimSpace(k), strings.TrimSpace(v)
+		switch k {
+		case "api":
+			n, err := strconv.ParseUint(v, 10, 32)
+			if err != nil {
+				return nil, fmt.Errorf("%s: invalid API version %s", s.Url, v)
+			}
+			s.MajorApiVersion = uint32(n)
+		case "operator":
+			s.Operator = v
+		case "start", "end":
+			t, err := time.Parse(time.RFC3339, v)
+			if err != nil {
+				return nil, fmt.Errorf("%s: invalid %s time %s: %w", s.Url, k, v, err)
+			}
+			if s.ValidFor == nil {
+				s.ValidFor = &pc.TimeRange{}
+			}
+			if k == "start" {
+				s.ValidFor.Start = timestamppb.New(t)
+			} else {
+				s.ValidFor.End = timestamppb.New(t)
+			}
+		default:
+			return nil, fmt.Errorf("%s: unknown attribute %s", s.Url, k)
+		}
+	}
+
+	return &s, nil
+}
+
+// newServiceConfig returns the selector configuration, count is only
+// allowed, and required, for EXACT.
+func newServiceConfig(selector string, count uint) (*ptr.ServiceConfiguration, error) {
+	v, ok := ptr.ServiceSelector_value[strings.ToUpper(selector)]
+	if !ok || v == int32(ptr.ServiceSelector_SERVICE_SELECTOR_UNDEFINED) {
+		return nil, fmt.Errorf("unknown selector %s, expected ALL, ANY or EXACT", selector)
+	}
+
+	var c = ptr.ServiceConfiguration{Selector: ptr.ServiceSelector(v)}
+	switch {
+	case c.Selector == ptr.ServiceSelector_EXACT && count == 0:
+		return nil, fmt.Errorf("EXACT requires a count")
+	case c.Selector != ptr.ServiceSelector_EXACT && count != 0:
+		return nil, fmt.Errorf("a count can only be used with EXACT")
+	}
+	c.Count = uint32(count)
+
+	return &c, nil
+}
+
+// ServiceDefaults are used for signing config services that do not
+// set them. They only apply to version 0.2 and later, version 0.1 has
+// no validity periods or operators.
+type ServiceDefaults struct {
+	// Start of the validity period, RFC 3339. Empty means now.
+	Start string
+	// Operator of the service, e.g. sigstore.dev.
+	Operator string
+}
+
+func addServiceFlags(fs *flag.FlagSet) *ServiceDefaults {
+	var d ServiceDefaults
+
+	fs.StringVar(&d.Start, "start", "", "Validity start for services without one. Defaults to now")
+	fs.StringVar(&d.Operator, "operator", "", "Operator for services without one, e.g. sigstore.dev")
+
+	return &d
+}
+
+// applyAll sets the defaults on all services of the signing config.
+func (d ServiceDefaults) applyAll(sc *ptr.SigningConfig) error {
+	for _, svcs := range [][]*ptr.Service{sc.CaUrls, sc.OidcUrls, sc.RekorTlogUrls, sc.TsaUrls} {
+		for _, s := range svcs {
+			if err := d.apply(s); err != nil {
+				return err
+			}
+		}
 	}
 
-	fmt.Println(string(buf))
+	return nil
+}
+
+// apply sets the validity start and operator of the service if it has
+// none. The operator is required, so it is an error if neither the
+// service nor the defaults have one.
+func (d ServiceDefaults) apply(s *ptr.Service) error {
+	var t = time.Now().UTC().Truncate(time.Second)
+
+	if s.Operator == "" {
+		if d.Operator == "" {
+			return fmt.Errorf("%s: no operator, set it with operator= or -operator", s.Url)
+		}
+		s.Operator = d.Operator
+	}
+	if s.ValidFor.GetStart() != nil {
+		return nil
+	}
+	if d.Start != "" {
+		var err error
+		if t, err = time.Parse(time.RFC3339, d.Start); err != nil {
+			return fmt.Errorf("invalid start time %s: %w", d.Start, err)
+		}
+	}
+	if s.ValidFor == nil {
+		s.ValidFor = &pc.TimeRange{}
+	}
+	s.ValidFor.Start = timestamppb.New(t)
 
 	return nil
 }
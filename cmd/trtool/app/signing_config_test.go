@@ -0,0 +1,80 @@
+package app
+
+import (
+	"testing"
+	"time"
+
+	pc "github.com/sigstore/protobuf-specs/gen/pb-go/common/v1"
+	ptr "github.com/sigstore/protobuf-specs/gen/pb-go/trustroot/v1"
+	"github.com/stretchr/testify/assert"
+	"google.golang.org/protobuf/types/known/timestamppb"
+)
+
+func TestParseService(t *testing.T) {
+	s, err := parseService("https://rekor.test;api=2;operator=test.com;start=2025-01-01T00:00:00Z")
+	assert.Nil(t, err)
+	assert.Equal(t, "https://rekor.test", s.GetUrl())
+	assert.Equal(t, uint32(2), s.GetMajorApiVersion())
+	assert.Equal(t, "test.com", s.GetOperator())
+	assert.Equal(t, int64(1735689600), s.GetValidFor().GetStart().GetSeconds())
+	assert.Nil(t, s.GetValidFor().GetEnd())
+
+	// Whitespace around keys and values is ignored
+	s, err = parseService("https://rekor.test; api= 2 ; start =2025-01-01T00:00:00Z")
+	assert.Nil(t, err)
+	assert.Equal(t, uint32(2), s.GetMajorApiVersion())
+	assert.Equal(t, int64(1735689600), s.GetValidFor().GetStart().GetSeconds())
+
+	_, err = parseService("")
+	assert.ErrorContains(t, err, "empty URL")
+	_, err = parseService("https://rekor.test;color=blue")
+	assert.ErrorContains(t, err, "unknown attribute color")
+}
+
+func TestNewServiceConfig(t *testing.T) {
+	c, err := newServiceConfig("exact", 2)
+	assert.Nil(t, err)
+	assert.Equal(t, "EXACT", c.GetSelector().String())
+	assert.Equal(t, uint32(2), c.GetCount())
+
+	_, err = newServiceConfig("EXACT", 0)
+	assert.NotNil(t, err)
+	_, err = newServiceConfig("ANY", 1)
+	assert.NotNil(t, err)
+	_, err = newServiceConfig("SOME", 0)
+	assert.NotNil(t, err)
+}
+
+func TestAddRootServices(t *testing.T) {
+	var now = time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)
+	var since = &pc.TimeRange{Start: timestamppb.New(now.AddDate(-1, 0, 0))}
+	var expired = &pc.TimeRange{Start: since.Start, End: timestamppb.New(now.AddDate(0, -1, 0))}
+	var tr = ptr.TrustedRoot{
+		CertificateAuthorities: []*ptr.CertificateAuthority{
+			{Uri: "https://old.fulcio.test", ValidFor: expired},
+			{Uri: "https://fulcio.test", ValidFor: since, Operator: "test.com"},
+		},
+		Tlogs: []*ptr.TransparencyLogInstance{
+			{BaseUrl: "https://rekor.test", PublicKey: &pc.PublicKey{ValidFor: since}},
+			{BaseUrl: "https://rekor.test", PublicKey: &pc.PublicKey{ValidFor: since}},
+			{BaseUrl: "https://rekor2.test", PublicKey: &pc.PublicKey{ValidFor: since}},
+		},
+	}
+	var sc = ptr.SigningConfig{
+		MediaType:     "application/vnd.dev.sigstore.signingconfig.v0.2+json",
+		RekorTlogUrls: []*ptr.Service{{Url: "https://rekor2.test", MajorApiVersion: 2}},
+	}
+
+	assert.Nil(t, addRootServices(&sc, &tr, now))
+	assert.Len(t, sc.CaUrls, 1)
+	assert.Equal(t, "https://fulcio.test", sc.CaUrls[0].GetUrl())
+	assert.Equal(t, "test.com", sc.CaUrls[0].GetOperator())
+	assert.Equal(t, since.Start.AsTime(), sc.CaUrls[0].GetValidFor().GetStart().AsTime())
+	assert.Len(t, sc.RekorTlogUrls, 2)
+	assert.Equal(t, uint32(2), sc.RekorTlogUrls[0].GetMajorApiVersion())
+	assert.Equal(t, "https://rekor.test", sc.RekorTlogUrls[1].GetUrl())
+	assert.Empty(t, sc.TsaUrls)
+
+	tr.TimestampAuthorities = []*ptr.CertificateAuthority{{ValidFor: since}}
+	assert.ErrorContains(t, addRootServices(&sc, &tr, now), "timestampAuthorities[0] has no URL")
+}
@@ -0,0 +1,413 @@
+package app
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+
+	"github.com/peterbourgon/ff/v3/ffcli"
+	ptr "github.com/sigstore/protobuf-specs/gen/pb-go/trustroot/v1"
+	"google.golang.org/genproto/googleapis/api/annotations"
+	"google.golang.org/protobuf/encoding/protojson"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/reflect/protoreflect"
+)
+
+// requiredFields are the fields clients can not do without, in
+// addition to the fields annotated as REQUIRED in protobuf-specs.
+var requiredFields = map[protoreflect.FullName][]protoreflect.Name{
+	"dev.sigstore.trustroot.v1.TrustedRoot":             {"media_type"},
+	"dev.sigstore.trustroot.v1.SigningConfig":           {"media_type"},
+	"dev.sigstore.trustroot.v1.ClientTrustConfig":       {"media_type", "trusted_root", "signing_config"},
+	"dev.sigstore.trustroot.v1.TransparencyLogInstance": {"base_url", "hash_algorithm", "public_key", "log_id"},
+	"dev.sigstore.trustroot.v1.CertificateAuthority":    {"subject", "cert_chain", "valid_for"},
+	"dev.sigstore.trustroot.v1.Service":                 {"url"},
+	"dev.sigstore.common.v1.PublicKey":                  {"raw_bytes", "key_details", "valid_for"},
+	"dev.sigstore.common.v1.X509CertificateChain":       {"certificates"},
+	"dev.sigstore.common.v1.TimeRange":                  {"start"},
+}
+
+// Finding is a validation problem at a JSON path.
+type Finding struct {
+	Path    string
+	Message string
+}
+
+func (f Finding) String() string {
+	return f.Path + ": " + f.Message
+}
+
+// ValidationError is returned by strict readers when a document has
+// validation findings.
+type ValidationError struct {
+	Findings []Finding
+}
+
+func (e *ValidationError) Error() string {
+	var lines = make([]string, len(e.Findings))
+
+	for i, f := range e.Findings {
+		lines[i] = f.String()
+	}
+
+	return "validation failed:\n" + strings.Join(lines, "\n")
+}
+
+func Validate() *ffcli.Command {
+	var (
+		flagset = flag.NewFlagSet("trtool validate", flag.ExitOnError)
+		file    = flagset.String("f", "", "Trusted root or signing config to validate")
+		in      = addInputFlags(flagset)
+	)
+
+	return &ffcli.Command{
+		Name:       "validate",
+		ShortUsage: "trtool validate -f file.json",
+		ShortHelp:  "Validate the schema of a trusted root or signing config",
+		LongHelp:   "Validate the schema of a trusted root or signing config. Unknown fields, duplicate keys, unknown media types and missing required fields are reported with their JSON path. The same checks are enabled on all readers with -strict",
+		FlagSet:    flagset,
+		Exec: func(ctx context.Context, args []string) error {
+			if *file == "" {
+				return flag.ErrHelp
+			}
+
+			b, err := os.ReadFile(*file)
+			if err != nil {
+				return err
+			}
+
+			return ValidateCmd(os.Stdout, b, *in)
+		},
+	}
+}
+
+// ValidateCmd validates a trusted root or signing config and writes
+// the findings to w.
+func ValidateCmd(w io.Writer, b []byte, in InputOptions) error {
+	var m = newDocument(documentKindOf(b, in))
+
+	findings, err := validate(b, m, in.Format)
+	if err != nil {
+		return err
+	}
+	for _, f := range findings {
+		fmt.Fprintln(w, f)
+	}
+	if len(findings) > 0 {
+		return errors.New("validation failed")
+	}
+
+	return nil
+}
+
+// validate parses b into m and returns all findings. An error is only
+// returned if the document can not be parsed at all.
+func validate(b []byte, m proto.Message, format string) ([]Finding, error) {
+	var findings []Finding
+	var err error
+
+	switch format {
+	case FormatProto:
+		if err = proto.Unmarshal(b, m); err != nil {
+			return nil, err
+		}
+		findings = unknownFields(m.ProtoReflect(), "$")
+	case FormatYAML:
+		if b, err = yamlToJSON(b); err != nil {
+			return nil, err
+		}
+		fallthrough
+	case "", FormatJSON:
+		if sc, ok := m.(*ptr.SigningConfig); ok && isV01MediaType(b) {
+			// Not described by protobuf-specs anymore, unknown
+			// fields are rejected by the decoder.
+			if err = unmarshalSigningConfigV01(b, sc); err != nil {
+				return []Finding{{"$", err.Error()}}, nil
+			}
+			return nil, nil
+		}
+		if findings, err = checkJSON(b, m.ProtoReflect().Descriptor()); err != nil {
+			return nil, err
+		}
+		// Unknown fields are already reported, continue with the
+		// semantic checks. Other structural problems make protojson
+		// fail, and are already reported with their path.
+		opts := protojson.UnmarshalOptions{DiscardUnknown: true}
+		if err = opts.Unmarshal(b, m); err != nil {
+			if len(findings) == 0 {
+				findings = append(findings, Finding{"$", err.Error()})
+			}
+			return findings, nil
+		}
+	default:
+		return nil, fmt.Errorf("unsupported input format %s", format)
+	}
+
+	findings = append(findings, checkMediaType(m)...)
+	findings = append(findings, checkRequired(m.ProtoReflect(), "$")...)
+
+	return findings, nil
+}
+
+func checkMediaType(m proto.Message) []Finding {
+	var r = m.ProtoReflect()
+	var fd = r.Descriptor().Fields().ByName("media_type")
+
+	if fd == nil || !r.Has(fd) {
+		// Reported as a missing required field
+		return nil
+	}
+	mt := r.Get(fd).String()
+	known := mediaTypes(documentKind(m))
+	if hasString(known, mt) {
+		return nil
+	}
+
+	return []Finding{{
+		Path: "$." + fd.JSONName(),
+		Message: fmt.Sprintf("unknown media type %s, expected one of %s",
+			mt, strings.Join(known, ", ")),
+	}}
+}
+
+// checkRequired walks all populated messages and reports missing
+// required fields.
+func checkRequired(m protoreflect.Message, path string) []Finding {
+	var findings []Finding
+	var md = m.Descriptor()
+
+	for _, name := range requiredFields[md.FullName()] {
+		fd := md.Fields().ByName(name)
+		if fd != nil && !m.Has(fd) {
+			findings = append(findings, Finding{path + "." + fd.JSONName(), "required field is missing"})
+		}
+	}
+	for i := 0; i < md.Fields().Len(); i++ {
+		fd := md.Fields().Get(i)
+		if !isAnnotatedRequired(fd) || m.Has(fd) || hasName(requiredFields[md.FullName()], fd.Name()) {
+			continue
+		}
+		findings = append(findings, Finding{path + "." + fd.JSONName(), "required field is missing"})
+	}
+
+	rangeMessages(m, path, func(c protoreflect.Message, p string) {
+		findings = append(findings, checkRequired(c, p)...)
+	})
+
+	return findings
+}
+
+// rangeMessages calls fn for each populated message field and list
+// element of m, in declaration order so findings are stable.
+func rangeMessages(m protoreflect.Message, path string, fn func(protoreflect.Message, string)) {
+	var fields = m.Descriptor().Fields()
+
+	for i := 0; i < fields.Len(); i++ {
+		fd := fields.Get(i)
+		if fd.Message() == nil || fd.IsMap() || !m.Has(fd) {
+			continue
+		}
+		p := path + "." + fd.JSONName()
+		if !fd.IsList() {
+			fn(m.Get(fd).Message(), p)
+			continue
+		}
+		l := m.Get(fd).List()
+		for j := 0; j < l.Len(); j++ {
+			fn(l.Get(j).Message(), fmt.Sprintf("%s[%d]", p, j))
+		}
+	}
+}
+
+func isAnnotatedRequired(fd protoreflect.FieldDescriptor) bool {
+	fbs, ok := proto.GetExtension(fd.Options(), annotations.E_FieldBehavior).([]annotations.FieldBehavior)
+	if !ok {
+		return false
+	}
+	for _, fb := range fbs {
+		if fb == annotations.FieldBehavior_REQUIRED {
+			return true
+		}
+	}
+
+	return false
+}
+
+func hasName(names []protoreflect.Name, n protoreflect.Name) bool {
+	for _, name := range names {
+		if name == n {
+			return true
+		}
+	}
+
+	return false
+}
+
+// unknownFields reports unknown fields in a message parsed from binary
+// protobuf.
+func unknownFields(m protoreflect.Message, path string) []Finding {
+	var findings []Finding
+
+	if len(m.GetUnknown()) > 0 {
+		findings = append(findings, Finding{path, "unknown fields"})
+	}
+	rangeMessages(m, path, func(c protoreflect.Message, p string) {
+		findings = append(findings, unknownFields(c, p)...)
+	})
+
+	return findings
+}
+
+// checkJSON walks the JSON document alongside the message descriptor
+// and reports duplicate keys, unknown fields and values of the wrong
+// kind. protojson rejects these too, but without a path and only the
+// first problem found.
+func checkJSON(b []byte, md protoreflect.MessageDescriptor) ([]Finding, error) {
+	var findings []Finding
+	var dec = json.NewDecoder(bytes.NewReader(b))
+
+	dec.UseNumber()
+	if err := checkJSONMessage(dec, md, "$", &findings); err != nil {
+		return nil, fmt.Errorf("invalid JSON: %w", err)
+	}
+	if dec.More() {
+		return nil, errors.New("invalid JSON: trailing data after document")
+	}
+
+	return findings, nil
+}
+
+func checkJSONMessage(dec *json.Decoder, md protoreflect.MessageDescriptor, path string, findings *[]Finding) error {
+	tok, err := dec.Token()
+	if err != nil {
+		return err
+	}
+	if tok == nil {
+		return nil
+	}
+	if tok != json.Delim('{') {
+		*findings = append(*findings, Finding{path, "expected an object"})
+		return skipJSON(dec, tok)
+	}
+
+	var seen = map[string]bool{}
+	for dec.More() {
+		tok, err = dec.Token()
+		if err != nil {
+			return err
+		}
+		key, ok := tok.(string)
+		if !ok {
+			return fmt.Errorf("%s: expected an object key", path)
+		}
+		p := path + "." + key
+		if seen[key] {
+			*findings = append(*findings, Finding{p, "duplicate key"})
+		}
+		seen[key] = true
+
+		fd := md.Fields().ByJSONName(key)
+		if fd == nil {
+			fd = md.Fields().ByName(protoreflect.Name(key))
+		}
+		if fd == nil {
+			*findings = append(*findings, Finding{p, "unknown field"})
+			if err = skipJSONValue(dec); err != nil {
+				return err
+			}
+			continue
+		}
+		if err = checkJSONField(dec, fd, p, findings); err != nil {
+			return err
+		}
+	}
+
+	// Consume the closing brace
+	_, err = dec.Token()
+	return err
+}
+
+func checkJSONField(dec *json.Decoder, fd protoreflect.FieldDescriptor, path string, findings *[]Finding) error {
+	if fd.IsList() {
+		tok, err := dec.Token()
+		if err != nil {
+			return err
+		}
+		if tok == nil {
+			return nil
+		}
+		if tok != json.Delim('[') {
+			*findings = append(*findings, Finding{path, "expected an array"})
+			return skipJSON(dec, tok)
+		}
+		for i := 0; dec.More(); i++ {
+			if err = checkJSONElement(dec, fd, fmt.Sprintf("%s[%d]", path, i), findings); err != nil {
+				return err
+			}
+		}
+		_, err = dec.Token()
+		return err
+	}
+
+	return checkJSONElement(dec, fd, path, findings)
+}
+
+func checkJSONElement(dec *json.Decoder, fd protoreflect.FieldDescriptor, path string, findings *[]Finding) error {
+	// Well known types have their own JSON representation, leave
+	// those to protojson.
+	if fd.Message() != nil && !fd.IsMap() &&
+		!strings.HasPrefix(string(fd.Message().FullName()), "google.protobuf.") {
+		return checkJSONMessage(dec, fd.Message(), path, findings)
+	}
+	if fd.Message() != nil {
+		return skipJSONValue(dec)
+	}
+
+	tok, err := dec.Token()
+	if err != nil {
+		return err
+	}
+	if d, ok := tok.(json.Delim); ok {
+		*findings = append(*findings, Finding{path, fmt.Sprintf("expected a %s value", fd.Kind())})
+		return skipJSON(dec, d)
+	}
+
+	return nil
+}
+
+func skipJSONValue(dec *json.Decoder) error {
+	tok, err := dec.Token()
+	if err != nil {
+		return err
+	}
+
+	return skipJSON(dec, tok)
+}
+
+// skipJSON consumes the rest of a value, given its first token.
+func skipJSON(dec *json.Decoder, tok json.Token) error {
+	if d, ok := tok.(json.Delim); !ok || (d != '{' && d != '[') {
+		return nil
+	}
+
+	for depth := 1; depth > 0; {
+		tok, err := dec.Token()
+		if err != nil {
+			return err
+		}
+		switch tok {
+		case json.Delim('{'), json.Delim('['):
+			depth++
+		case json.Delim('}'), json.Delim(']'):
+			depth--
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,68 @@
+package app
+
+import (
+	"bytes"
+	"errors"
+	"testing"
+
+	ptr "github.com/sigstore/protobuf-specs/gen/pb-go/trustroot/v1"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestValidate(t *testing.T) {
+	var out bytes.Buffer
+	var doc = []byte(`{
+  "mediaType": "application/vnd.dev.sigstore.trustedroot.v0.1+json",
+  "tlogs": [
+    {
+      "baseUrl": "https://rekor.test",
+      "baseUrl": "https://rekor2.test",
+      "color": "blue"
+    }
+  ],
+  "certificateAuthorities": {}
+}`)
+
+	err := ValidateCmd(&out, doc, DefaultInputOptions)
+	assert.NotNil(t, err)
+	assert.Equal(t, `$.tlogs[0].baseUrl: duplicate key
+$.tlogs[0].color: unknown field
+$.certificateAuthorities: expected an array
+`, out.String())
+
+	out.Reset()
+	doc = []byte(`{
+  "mediaType": "application/vnd.dev.sigstore.trustedroot.v0.9+json",
+  "tlogs": [
+    {
+      "baseUrl": "https://rekor.test",
+      "hashAlgorithm": "SHA2_256",
+      "publicKey": {
+        "rawBytes": "AAEC",
+        "keyDetails": "PKIX_ED25519",
+        "validFor": {}
+      },
+      "logId": {}
+    }
+  ]
+}`)
+	err = ValidateCmd(&out, doc, DefaultInputOptions)
+	assert.NotNil(t, err)
+	assert.Equal(t, `$.mediaType: unknown media type application/vnd.dev.sigstore.trustedroot.v0.9+json, expected one of application/vnd.dev.sigstore.trustedroot+json;version=0.1, application/vnd.dev.sigstore.trustedroot.v0.1+json, application/vnd.dev.sigstore.trustedroot.v0.2+json
+$.tlogs[0].publicKey.validFor.start: required field is missing
+$.tlogs[0].logId.keyId: required field is missing
+`, out.String())
+
+	// The same document is rejected by strict readers
+	var tr ptr.TrustedRoot
+	var verr *ValidationError
+	assert.Nil(t, unmarshalProto(doc, &tr, DefaultInputOptions))
+	err = unmarshalProto(doc, &tr, InputOptions{Format: FormatJSON, Strict: true})
+	assert.True(t, errors.As(err, &verr))
+	assert.Equal(t, 3, len(verr.Findings))
+
+	out.Reset()
+	doc = []byte(`{"mediaType": "application/vnd.dev.sigstore.trustedroot.v0.1+json"}`)
+	assert.Nil(t, ValidateCmd(&out, doc, DefaultInputOptions))
+	assert.Equal(t, "", out.String())
+}
@@ -3,15 +3,17 @@ package app
 import (
 	"context"
 	"crypto/x509"
+	"crypto/x509/pkix"
 	"errors"
 	"flag"
 	"fmt"
+	"io"
 	"os"
+	"slices"
 	"time"
 
 	"github.com/peterbourgon/ff/v3/ffcli"
 	"github.com/sigstore/protobuf-specs/gen/pb-go/trustroot/v1"
-	"google.golang.org/protobuf/encoding/protojson"
 )
 
 func Verify() *ffcli.Command {
@@ -19,13 +21,14 @@ func Verify() *ffcli.Command {
 		flagset = flag.NewFlagSet("trtool verify", flag.ExitOnError)
 		root    = flagset.String("f", "", "Trusted root to verify")
 		verbose = flagset.Bool("v", false, "verbose mode")
+		in      = addInputFlags(flagset)
 	)
 
 	return &ffcli.Command{
 		Name:       "verify",
 		ShortUsage: "trtool verify -f file.json",
 		ShortHelp:  "Verify trusted root",
-		LongHelp:   "Verify trusted root",
+		LongHelp:   "Verify trusted root, or the trusted root of a client trust config",
 		FlagSet:    flagset,
 		Exec: func(ctx context.Context, args []string) error {
 			if *root == "" {
@@ -37,24 +40,18 @@ func Verify() *ffcli.Command {
 				return err
 			}
 
-			return VerifyCmd(b, *verbose)
+			return VerifyCmd(b, *in, *verbose)
 		},
 	}
 }
 
-func VerifyCmd(b []byte, verbose bool) error {
-	var trustRoot v1.TrustedRoot
-	var err error
-	var valid = true
-
-	if err = protojson.Unmarshal(b, &trustRoot); err != nil {
+func VerifyCmd(b []byte, in InputOptions, verbose bool) error {
+	trustRoot, err := unmarshalTrustedRoot(b, in)
+	if err != nil {
 		return err
 	}
 
-	valid = VerifyCertChains(trustRoot.CertificateAuthorities, verbose)
-	valid = VerifyCertChains(trustRoot.TimestampAuthorities, verbose) && valid
-
-	if !valid {
+	if !VerifyTrustedRoot(os.Stdout, trustRoot, verbose) {
 		return errors.New("verification failed")
 	} else if verbose {
 		fmt.Println("Trusted root is valid")
@@ -63,23 +60,41 @@ func VerifyCmd(b []byte, verbose bool) error {
 	return nil
 }
 
-func VerifyCertChains(cas []*v1.CertificateAuthority, verbose bool) bool {
+// VerifyTrustedRoot verifies all certificate chains in the trusted
+// root, and the tiling of log shards, and writes any findings to w.
+func VerifyTrustedRoot(w io.Writer, tr *v1.TrustedRoot, verbose bool) bool {
+	var valid = true
+
+	valid = VerifyCertChains(w, tr.CertificateAuthorities, verbose)
+	valid = VerifyCertChains(w, tr.TimestampAuthorities, verbose) && valid
+	// The shard families are guessed from the URIs, so this only
+	// warns, add-shards checks the family of its template.
+	for _, f := range slices.Concat(
+		checkShards("tlogs", tr.Tlogs, shardFamily),
+		checkShards("ctlogs", tr.Ctlogs, shardFamily)) {
+		fmt.Fprintf(w, "WARNING: %s\n", f)
+	}
+
+	return valid
+}
+
+func VerifyCertChains(w io.Writer, cas []*v1.CertificateAuthority, verbose bool) bool {
 	var valid = true
 	var prev *v1.CertificateAuthority
 
 	for _, ca := range cas {
-		if ok := VerifyCertChain(ca, verbose); !ok {
+		if ok := VerifyCertChain(w, ca, verbose); !ok {
 			valid = false
 		}
 		// Verify the order. They SHOULD be orderd from oldes to
 		// newest (active)
 		if prev != nil {
-			if ca.ValidFor.Start.AsTime().Before(prev.ValidFor.Start.AsTime()) {
-				fmt.Printf("WARING: %s [%s] should be listed after %s [%s]\n",
+			if ca.GetValidFor().GetStart().AsTime().Before(prev.GetValidFor().GetStart().AsTime()) {
+				fmt.Fprintf(w, "WARING: %s [%s] should be listed after %s [%s]\n",
 					ca.Uri,
-					ca.ValidFor.Start.AsTime().Format(time.RFC3339),
+					ca.GetValidFor().GetStart().AsTime().Format(time.RFC3339),
 					prev.Uri,
-					prev.ValidFor.Start.AsTime().Format(time.RFC3339),
+					prev.GetValidFor().GetStart().AsTime().Format(time.RFC3339),
 				)
 			}
 		}
@@ -89,59 +104,64 @@ func VerifyCertChains(cas []*v1.CertificateAuthority, verbose bool) bool {
 	return valid
 }
 
-func VerifyCertChain(ca *v1.CertificateAuthority, verbose bool) bool {
+func VerifyCertChain(w io.Writer, ca *v1.CertificateAuthority, verbose bool) bool {
 	var parsed []*x509.Certificate
 	var valid = true
 
 	if verbose {
-		fmt.Printf("Verifying OU='%s' CN='%s' of length %d\n",
-			ca.Subject.Organization,
-			ca.Subject.CommonName,
-			len(ca.CertChain.Certificates),
+		fmt.Fprintf(w, "Verifying OU='%s' CN='%s' of length %d\n",
+			ca.GetSubject().GetOrganization(),
+			ca.GetSubject().GetCommonName(),
+			len(ca.GetCertChain().GetCertificates()),
 		)
 	}
+	if len(ca.GetCertChain().GetCertificates()) == 0 {
+		fmt.Fprintf(w, "Empty certificate chain for %s\n", ca.GetUri())
+		return false
+	}
 
 	var child *x509.Certificate
 	for i, cert := range ca.CertChain.Certificates {
-		c, err := x509.ParseCertificate(cert.RawBytes)
+		c, err := x509.ParseCertificate(cert.GetRawBytes())
 		if err != nil {
-			panic(err)
+			fmt.Fprintf(w, "Invalid certificate at pos %d of %s: %v\n", i, ca.GetUri(), err)
+			return false
 		}
 
 		// Verify that the CA's start time is equal to or later than
 		// the certificate's not before.
-		if c.NotBefore.After(ca.ValidFor.Start.AsTime()) {
-			fmt.Printf("Error verifying certificate: %s\n", c.Subject.CommonName)
-			fmt.Printf("Bundle's validity.start %s\n", ca.ValidFor.Start.AsTime())
-			fmt.Printf("Certificate's not before %s\n", c.NotBefore)
+		if c.NotBefore.After(ca.GetValidFor().GetStart().AsTime()) {
+			fmt.Fprintf(w, "Error verifying certificate: %s\n", c.Subject.CommonName)
+			fmt.Fprintf(w, "Bundle's validity.start %s\n", ca.GetValidFor().GetStart().AsTime())
+			fmt.Fprintf(w, "Certificate's not before %s\n", c.NotBefore)
 
-			fmt.Println("Certificate's 'not before' must be before the CA's validity time as specified in the bundle")
+			fmt.Fprintln(w, "Certificate's 'not before' must be before the CA's validity time as specified in the bundle")
 			valid = false
 		}
 		// Verify that the CA's start time is not after the certificate's
 		// not before
-		if ca.ValidFor.Start.AsTime().After(c.NotAfter) {
-			fmt.Printf("Certificate's 'not after' %s must be before CA's start time %s\n",
-				c.NotAfter, ca.ValidFor.Start.AsTime())
+		if ca.GetValidFor().GetStart().AsTime().After(c.NotAfter) {
+			fmt.Fprintf(w, "Certificate's 'not after' %s must be before CA's start time %s\n",
+				c.NotAfter, ca.GetValidFor().GetStart().AsTime())
 			valid = false
 		}
 		// Verify that the CA's end time is not after the certificate's
 		// not after.
-		if ca.ValidFor.End != nil && ca.ValidFor.End.AsTime().After(c.NotAfter) {
-			fmt.Println("Certificate's 'not after' is greater than the CA's validity time as specified in the bundle")
+		if ca.GetValidFor().GetEnd() != nil && ca.GetValidFor().GetEnd().AsTime().After(c.NotAfter) {
+			fmt.Fprintln(w, "Certificate's 'not after' is greater than the CA's validity time as specified in the bundle")
 			valid = false
 		}
 
 		if verbose {
-			fmt.Printf("  Loaded OU='%s' CN='%s' CA:%v MaxPathLen %d at pos %d\n",
-				c.Subject.Organization[0],
+			fmt.Fprintf(w, "  Loaded OU='%s' CN='%s' CA:%v MaxPathLen %d at pos %d\n",
+				organization(c.Subject),
 				c.Subject.CommonName,
 				c.IsCA,
 				c.MaxPathLen,
 				i,
 			)
-			fmt.Printf("    issuer OU='%s' CN='%s'\n",
-				c.Issuer.Organization[0],
+			fmt.Fprintf(w, "    issuer OU='%s' CN='%s'\n",
+				organization(c.Issuer),
 				c.Issuer.CommonName,
 			)
 		}
@@ -151,33 +171,33 @@ func VerifyCertChain(ca *v1.CertificateAuthority, verbose bool) bool {
 			// The order is leaf, intermediate(*), root
 			// So when verifying a cert, make sure that the
 			// previous certificate was signed by the current one.
-			if child.Issuer.Organization[0] != c.Subject.Organization[0] {
-				fmt.Printf("Found issuer organization '%s', expected '%s'\n",
-					child.Issuer.Organization[0],
-					c.Subject.Organization,
+			if organization(child.Issuer) != organization(c.Subject) {
+				fmt.Fprintf(w, "Found issuer organization '%s', expected '%s'\n",
+					organization(child.Issuer),
+					organization(c.Subject),
 				)
 				valid = false
 			}
 			if child.Issuer.CommonName != c.Subject.CommonName {
-				fmt.Printf("Found issuer common name '%s', expected '%s'\n",
+				fmt.Fprintf(w, "Found issuer common name '%s', expected '%s'\n",
 					child.Issuer.CommonName,
 					c.Subject.CommonName,
 				)
 				valid = false
 			}
 			if len(child.AuthorityKeyId) != len(c.SubjectKeyId) {
-				fmt.Printf("Unexpected authority key id\n")
+				fmt.Fprintf(w, "Unexpected authority key id\n")
 				valid = false
 			}
 			for i := range child.AuthorityKeyId {
 				if i >= len(c.SubjectKeyId) {
-					fmt.Printf("WARNING: missing SubjectKeyId on %s\n",
+					fmt.Fprintf(w, "WARNING: missing SubjectKeyId on %s\n",
 						c.Subject.CommonName,
 					)
 					break
 				}
 				if child.AuthorityKeyId[i] != c.SubjectKeyId[i] {
-					fmt.Printf("Unexpected authority key id\n")
+					fmt.Fprintf(w, "Unexpected authority key id\n")
 					valid = false
 					break
 				}
@@ -191,27 +211,37 @@ func VerifyCertChain(ca *v1.CertificateAuthority, verbose bool) bool {
 	// The last certificate is the root, verify that the subject matches
 	root := parsed[len(parsed)-1]
 	if !root.IsCA {
-		fmt.Println("expected root certificate last")
+		fmt.Fprintln(w, "expected root certificate last")
 		valid = false
 	}
 
-	if root.Subject.Organization[0] != ca.Subject.Organization {
-		fmt.Printf("Found organization '%s', expected '%s'\n",
-			root.Subject.Organization[0],
-			ca.Subject.Organization,
+	if organization(root.Subject) != ca.GetSubject().GetOrganization() {
+		fmt.Fprintf(w, "Found organization '%s', expected '%s'\n",
+			organization(root.Subject),
+			ca.GetSubject().GetOrganization(),
 		)
 		valid = false
 	}
-	if root.Subject.CommonName != ca.Subject.CommonName {
-		fmt.Printf("Found common name '%s', expected '%s'\n",
+	if root.Subject.CommonName != ca.GetSubject().GetCommonName() {
+		fmt.Fprintf(w, "Found common name '%s', expected '%s'\n",
 			root.Subject.CommonName,
-			ca.Subject.CommonName,
+			ca.GetSubject().GetCommonName(),
 		)
 		valid = false
 	}
 	if verbose {
-		fmt.Printf("------------------------------------------------------------------------\n")
+		fmt.Fprintf(w, "------------------------------------------------------------------------\n")
 	}
 
 	return valid
 }
+
+// organization returns the first organization of the name, or the
+// empty string if it has none.
+func organization(n pkix.Name) string {
+	if len(n.Organization) == 0 {
+		return ""
+	}
+
+	return n.Organization[0]
+}
@@ -0,0 +1,248 @@
+package app
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+
+	ptr "github.com/sigstore/protobuf-specs/gen/pb-go/trustroot/v1"
+	"google.golang.org/protobuf/proto"
+)
+
+// Document kinds, as used in the media types.
+const (
+	KindTrustedRoot       = "trustedroot"
+	KindSigningConfig     = "signingconfig"
+	KindClientTrustConfig = "clienttrustconfig"
+)
+
+// Versions written by init and sc-init unless -version is given.
+const (
+	DefaultTrustedRootVersion   = "0.1"
+	DefaultSigningConfigVersion = "0.2"
+)
+
+// schemaVersion is a version of the trusted root or signing config
+// schema and the media type it is identified by.
+type schemaVersion struct {
+	Kind      string
+	Version   string
+	MediaType string
+	// Aliases are other spellings of the media type, accepted when
+	// reading but never written.
+	Aliases []string
+}
+
+var schemaVersions = []schemaVersion{
+	// The legacy spelling is kept for verifiers matching on it
+	{
+		Kind:      KindTrustedRoot,
+		Version:   "0.1",
+		MediaType: "application/vnd.dev.sigstore.trustedroot+json;version=0.1",
+		Aliases:   []string{"application/vnd.dev.sigstore.trustedroot.v0.1+json"},
+	},
+	{
+		Kind:      KindTrustedRoot,
+		Version:   "0.2",
+		MediaType: "application/vnd.dev.sigstore.trustedroot.v0.2+json",
+	},
+	{
+		Kind:      KindSigningConfig,
+		Version:   "0.1",
+		MediaType: "application/vnd.dev.sigstore.signingconfig.v0.1+json",
+	},
+	{
+		Kind:      KindSigningConfig,
+		Version:   "0.2",
+		MediaType: "application/vnd.dev.sigstore.signingconfig.v0.2+json",
+	},
+	{
+		Kind:      KindClientTrustConfig,
+		Version:   "0.1",
+		MediaType: "application/vnd.dev.sigstore.clienttrustconfig.v0.1+json",
+	},
+}
+
+// documentKind returns the kind of the message, or an empty string for
+// messages that are not documents.
+func documentKind(m proto.Message) string {
+	switch m.(type) {
+	case *ptr.TrustedRoot:
+		return KindTrustedRoot
+	case *ptr.SigningConfig:
+		return KindSigningConfig
+	case *ptr.ClientTrustConfig:
+		return KindClientTrustConfig
+	}
+
+	return ""
+}
+
+// lookupVersion returns the named version of a kind.
+func lookupVersion(kind, version string) (schemaVersion, error) {
+	for _, v := range schemaVersions {
+		if v.Kind == kind && v.Version == version {
+			return v, nil
+		}
+	}
+
+	return schemaVersion{}, fmt.Errorf("unknown %s version %s, expected one of %s",
+		kind, version, strings.Join(versionNames(kind), ", "))
+}
+
+// versionOf returns the version identified by a media type.
+func versionOf(kind, mediaType string) (schemaVersion, bool) {
+	for _, v := range schemaVersions {
+		if v.Kind != kind {
+			continue
+		}
+		if v.MediaType == mediaType || hasString(v.Aliases, mediaType) {
+			return v, true
+		}
+	}
+
+	return schemaVersion{}, false
+}
+
+func versionNames(kind string) []string {
+	var names []string
+
+	for _, v := range schemaVersions {
+		if v.Kind == kind {
+			names = append(names, v.Version)
+		}
+	}
+
+	return names
+}
+
+// mediaTypes returns all media types accepted for a kind.
+func mediaTypes(kind string) []string {
+	var mts []string
+
+	for _, v := range schemaVersions {
+		if v.Kind == kind {
+			mts = append(mts, v.MediaType)
+			mts = append(mts, v.Aliases...)
+		}
+	}
+
+	return mts
+}
+
+func hasString(ss []string, s string) bool {
+	for _, v := range ss {
+		if v == s {
+			return true
+		}
+	}
+
+	return false
+}
+
+// signingConfigV01 is the v0.1 signing config. It is no longer part of
+// protobuf-specs, so it is read into the v0.2 message with a v0.1
+// media type, services without validity and ALL as the selector.
+type signingConfigV01 struct {
+	MediaType string   `json:"mediaType"`
+	CaURL     string   `json:"caUrl,omitempty"`
+	OidcURL   string   `json:"oidcUrl,omitempty"`
+	TlogURLs  []string `json:"tlogUrls,omitempty"`
+	TsaURLs   []string `json:"tsaUrls,omitempty"`
+}
+
+func isSigningConfigV01(sc *ptr.SigningConfig) bool {
+	v, ok := versionOf(KindSigningConfig, sc.GetMediaType())
+	return ok && v.Version == "0.1"
+}
+
+// unmarshalSigningConfigV01 parses a v0.1 signing config in JSON.
+func unmarshalSigningConfigV01(b []byte, sc *ptr.SigningConfig) error {
+	var v01 signingConfigV01
+
+	dec := json.NewDecoder(bytes.NewReader(b))
+	dec.DisallowUnknownFields()
+	if err := dec.Decode(&v01); err != nil {
+		return err
+	}
+
+	proto.Reset(sc)
+	sc.MediaType = v01.MediaType
+	if v01.CaURL != "" {
+		sc.CaUrls = []*ptr.Service{{Url: v01.CaURL, MajorApiVersion: 1}}
+	}
+	if v01.OidcURL != "" {
+		sc.OidcUrls = []*ptr.Service{{Url: v01.OidcURL, MajorApiVersion: 1}}
+	}
+	for _, u := range v01.TlogURLs {
+		sc.RekorTlogUrls = append(sc.RekorTlogUrls, &ptr.Service{Url: u, MajorApiVersion: 1})
+	}
+	for _, u := range v01.TsaURLs {
+		sc.TsaUrls = append(sc.TsaUrls, &ptr.Service{Url: u, MajorApiVersion: 1})
+	}
+	// v0.1 clients used all listed logs and TSAs
+	if len(sc.RekorTlogUrls) > 0 {
+		sc.RekorTlogConfig = &ptr.ServiceConfiguration{Selector: ptr.ServiceSelector_ALL}
+	}
+	if len(sc.TsaUrls) > 0 {
+		sc.TsaConfig = &ptr.ServiceConfiguration{Selector: ptr.ServiceSelector_ALL}
+	}
+
+	return nil
+}
+
+// marshalSigningConfigV01 returns compact JSON for a v0.1 signing
+// config. Anything v0.1 can not express is an error.
+func marshalSigningConfigV01(sc *ptr.SigningConfig) ([]byte, error) {
+	var errs []error
+	var v01 = signingConfigV01{MediaType: sc.GetMediaType()}
+
+	single := func(name string, svcs []*ptr.Service) string {
+		if len(svcs) > 1 {
+			errs = append(errs, fmt.Errorf("%s: v0.1 supports a single service, found %d", name, len(svcs)))
+		}
+		if len(svcs) == 0 {
+			return ""
+		}
+		return svcs[0].GetUrl()
+	}
+	urls := func(name string, svcs []*ptr.Service) []string {
+		var us []string
+		for i, s := range svcs {
+			us = append(us, s.GetUrl())
+			p := fmt.Sprintf("%s[%d]", name, i)
+			if s.GetValidFor() != nil {
+				errs = append(errs, fmt.Errorf("%s.validFor: not supported by v0.1", p))
+			}
+			if s.GetMajorApiVersion() != 1 {
+				errs = append(errs, fmt.Errorf("%s.majorApiVersion: v0.1 only supports version 1, found %d", p, s.GetMajorApiVersion()))
+			}
+			if s.GetOperator() != "" {
+				errs = append(errs, fmt.Errorf("%s.operator: not supported by v0.1", p))
+			}
+		}
+		return us
+	}
+	config := func(name string, c *ptr.ServiceConfiguration) {
+		if c != nil && (c.GetSelector() != ptr.ServiceSelector_ALL || c.GetCount() != 0) {
+			errs = append(errs, fmt.Errorf("%s: v0.1 always uses all services, found %s", name, c.GetSelector()))
+		}
+	}
+
+	urls("caUrls", sc.GetCaUrls())
+	urls("oidcUrls", sc.GetOidcUrls())
+	v01.CaURL = single("caUrls", sc.GetCaUrls())
+	v01.OidcURL = single("oidcUrls", sc.GetOidcUrls())
+	v01.TlogURLs = urls("rekorTlogUrls", sc.GetRekorTlogUrls())
+	v01.TsaURLs = urls("tsaUrls", sc.GetTsaUrls())
+	config("rekorTlogConfig", sc.GetRekorTlogConfig())
+	config("tsaConfig", sc.GetTsaConfig())
+
+	if len(errs) > 0 {
+		return nil, fmt.Errorf("signing config can not be written as v0.1 without losing data:\n%w", errors.Join(errs...))
+	}
+
+	return json.Marshal(v01)
+}
@@ -21,8 +21,35 @@ func main() {
 		Subcommands: []*ffcli.Command{
 			app.Verify(),
 			app.Add(),
+			app.AddShards(),
+			app.Apply(),
 			app.InitRoot(),
 			app.SCInit(),
+			app.SCAdd(),
+			app.SCRemove(),
+			app.SCSet(),
+			app.SCVerify(),
+			app.SCSelect(),
+			app.Consistency(),
+			app.ClientConfig(),
+			app.Split(),
+			app.Build(),
+			app.Report(),
+			app.Fmt(),
+			app.Get(),
+			app.Set(),
+			app.Validate(),
+			app.Migrate(),
+			app.ImportTUFTargets(),
+			app.ExportCosign(),
+			app.ExportCerts(),
+			app.ExportTrustStore(),
+			app.ExportK8s(),
+			app.ImportK8s(),
+			app.ImportResponse(),
+			app.ImportCTLogList(),
+			app.ExportCTLogList(),
+			app.ExportNoteVerifiers(),
 		},
 		Exec: func(context.Context, []string) error {
 			return flag.ErrHelp
@@ -1,18 +1,20 @@
 module github.com/kommendorkapten/trtool
 
-go 1.20
+go 1.23.0
 
 require (
+	github.com/BurntSushi/toml v1.5.0
 	github.com/peterbourgon/ff/v3 v3.4.0
-	github.com/sigstore/protobuf-specs v0.3.3-0.20240822155708-ea7269b6033a
+	github.com/sigstore/protobuf-specs v0.5.1
 	github.com/stretchr/testify v1.9.0
-	google.golang.org/protobuf v1.34.2
+	google.golang.org/genproto/googleapis/api v0.0.0-20250528174236-200df99c418a
+	google.golang.org/protobuf v1.36.11
+	gopkg.in/yaml.v3 v3.0.1
+	software.sslmate.com/src/go-pkcs12 v0.5.0
 )
 
 require (
 	github.com/davecgh/go-spew v1.1.1 // indirect
 	github.com/pmezard/go-difflib v1.0.0 // indirect
-	google.golang.org/genproto v0.0.0-20230706204954-ccb25ca9f130 // indirect
-	google.golang.org/genproto/googleapis/api v0.0.0-20230726155614-23370e0ffb3e // indirect
-	gopkg.in/yaml.v3 v3.0.1 // indirect
+	golang.org/x/crypto v0.11.0 // indirect
 )